@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"backfill-tool/internal"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configOutput string
+	configForce  bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create and check YAML run configuration files",
+	Long: `Create and check YAML run configuration files for 'backfill-tool run --config'.
+
+A configuration file holds everything a run needs: collection, CSV, workers,
+retries, rate limits, HTTP/TLS/proxy settings, outputs, auth overrides,
+variable files and item filters. Flags given on the command line override it.`,
+	Example: `  # Write a commented template and edit it
+  backfill-tool config init -o backfill.yaml
+
+  # Check a file without running it
+  backfill-tool config validate backfill.yaml`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Print a commented configuration template",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configOutput == "" {
+			fmt.Print(internal.ConfigTemplate)
+			return
+		}
+		if _, err := os.Stat(configOutput); err == nil && !configForce {
+			exitWithError(fmt.Errorf("%s already exists (use --force to overwrite)", configOutput))
+		}
+		if err := os.WriteFile(configOutput, []byte(internal.ConfigTemplate), 0644); err != nil {
+			exitWithError(fmt.Errorf("error writing %s: %v", configOutput, err))
+		}
+		fmt.Printf("📝 Wrote %s\n", configOutput)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Check a configuration file against the schema",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := internal.LoadRunConfig(args[0]); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("✓ %s is valid\n", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd, configValidateCmd)
+	configInitCmd.Flags().StringVarP(&configOutput, "output", "o", "", "Write the template to this file instead of stdout")
+	configInitCmd.Flags().BoolVar(&configForce, "force", false, "Overwrite an existing file")
+}
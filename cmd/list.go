@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"backfill-tool/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var listCollection string
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the item tree of a Postman collection",
+	Long: `Print the folders and requests of a Postman collection with their indices and methods.
+
+Use the same --item, --folder and --exclude selectors as 'run' to preview which
+requests a run would send; skipped requests are shown dimmed.`,
+	Example: `  # Show every folder and request
+  backfill-tool list -c collection.json
+
+  # Preview a selection
+  backfill-tool list -c collection.json --folder Users --exclude 'Users/Delete*'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.ListCollection(listCollection, selectItems, selectFolders, excludeItems); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVarP(&listCollection, "collection", "c", "", "Path to Postman collection JSON file (required)")
+	listCmd.MarkFlagRequired("collection")
+	listCmd.Flags().StringArrayVar(&selectItems, "item", nil, "Select requests matching this name, Folder/Item path or glob (repeatable)")
+	listCmd.Flags().StringArrayVar(&selectFolders, "folder", nil, "Select requests inside folders matching this name, path or glob (repeatable)")
+	listCmd.Flags().StringArrayVar(&excludeItems, "exclude", nil, "Skip requests or folders matching this name, path or glob (repeatable)")
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"backfill-tool/internal"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayHeaders    []string
+	replayFailedOnly bool
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Re-send recorded requests exactly as they were sent",
+	Long: `Re-send the requests recorded by 'backfill-tool run --record-requests'.
+
+The file is either a failed request CSV saved with --record-requests or a run
+log written with --record-requests --log-format json. Each recorded request is
+sent again with its method, URL, headers and body as they were rendered, without
+the collection or the CSV data. Requests are grouped into items by the item that
+sent them, and go through the same workers, retries, rate limit and outputs as
+a run.
+
+Secrets are masked when requests are recorded and cannot be sent again:
+  • {{secret:...}} references are kept in the recording and resolved again
+  • Other masked headers must be given with --header or --bearer-token
+  • Requests with masked values in their URL or body fail without being sent
+
+Every request gets a new traceparent. Requests signed with AWS Signature, Hawk
+or Digest auth cannot be replayed: the signature covers the time it was made
+and its credentials are masked. They fail without being sent; send those rows
+again with 'backfill-tool run'.
+
+Failures of a replay are saved with their recorded requests, so a replay's
+failed request CSV can be replayed in turn.`,
+
+	Example: `  # Re-send the failures of a run
+  backfill-tool run -c collection.json -s data.csv --record-requests
+  backfill-tool replay failed_requests_Create_User_20251103_114230.csv
+
+  # Give the credentials that were masked when recording
+  backfill-tool replay failed.csv -a "$API_TOKEN" --header "X-Api-Key: $API_KEY"
+
+  # Only the failed requests of a JSON run log
+  backfill-tool replay backfill.log --failed-only -t 5 --retries 3`,
+
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get global flags
+		verbose, _ := cmd.Flags().GetCount("verbose")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		// Limiting verbose output to failures asks for it
+		if verboseFailures && verbose == 0 {
+			verbose = internal.VerboseRequests
+		}
+
+		// Show startup info
+		if !quiet {
+			fmt.Println("🚀 Backfill Tool v2.3.0")
+			fmt.Printf("⚙️  Workers: %d\n", threads)
+			if metricsFile != "" {
+				fmt.Printf("📈 Metrics: %s\n", metricsFile)
+			}
+			fmt.Println()
+		}
+
+		config := internal.RunConfig{
+			Threads:     threads,
+			MetricsFile: metricsFile,
+			HTMLReport:  htmlReport,
+			JUnitFile:   junitFile,
+			Verbose:     verbose,
+			Quiet:       quiet,
+			BearerToken: bearerToken,
+			LogFormat:   logFormat,
+			LogFile:     logFile,
+
+			ParallelItems: parallelItems,
+			WorkerBudget:  workerBudget,
+
+			VerboseFailures: verboseFailures,
+
+			RequestTimeout:        requestTimeout,
+			DialTimeout:           dialTimeout,
+			TLSHandshakeTimeout:   tlsHandshakeTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       idleConnTimeout,
+			MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+			DisableKeepAlives:     disableKeepAlives,
+			HTTPVersion:           httpVersion,
+
+			CACertFile:         caCert,
+			ClientCertFile:     clientCert,
+			ClientKeyFile:      clientKey,
+			HostClientCerts:    hostClientCerts,
+			TLSMinVersion:      tlsMinVersion,
+			TLSServerName:      tlsServerName,
+			InsecureSkipVerify: insecureSkipVerify,
+
+			Proxy:   proxyURL,
+			NoProxy: noProxy,
+			DryRun:  dryRun,
+
+			RedactPatterns: redactPatterns,
+			RedactColumns:  redactColumns,
+
+			VaultFile: vaultFile,
+
+			Retries:         retries,
+			RetryBackoff:    retryBackoff,
+			RetryMaxBackoff: retryMaxBackoff,
+			RetryOn:         retryOn,
+			RateLimit:       rateLimit,
+			RateLimitBurst:  rateLimitBurst,
+
+			RunID:     runID,
+			OutputDir: outputDir,
+
+			TraceEndpoint:    otlpEndpoint,
+			TraceFile:        otlpFile,
+			TraceServiceName: otlpServiceName,
+
+			ReplayHeaders:    replayHeaders,
+			ReplayFailedOnly: replayFailedOnly,
+		}
+
+		internal.Replay(args[0], config)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().IntVarP(&threads, "threads", "t", 10, "Number of concurrent worker threads (1-100)")
+	replayCmd.Flags().IntVar(&parallelItems, "parallel-items", 1, "Replay this many items at the same time (1 = one after another)")
+	replayCmd.Flags().StringVar(&workerBudget, "worker-budget", internal.WorkerBudgetShared, "shared: --threads requests in flight across all parallel items; per-item: --threads workers for each item")
+	replayCmd.Flags().BoolVar(&replayFailedOnly, "failed-only", false, "From a run log, replay only the requests that failed")
+
+	// Output configuration
+	addOutputFlags(replayCmd)
+
+	// Authentication
+	replayCmd.Flags().StringVarP(&bearerToken, "bearer-token", "a", "", "Bearer token sent in place of the recorded Authorization header")
+	replayCmd.Flags().StringArrayVar(&replayHeaders, "header", nil, "Header sent in place of the recorded one of the same name, as 'Name: value' (repeatable, may use {{secret:...}})")
+
+	addSendFlags(replayCmd)
+
+	replayCmd.Flags().StringVar(&runID, "run-id", "", "ID of this replay, recorded in the metrics (default: generated)")
+	replayCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print every request that would be replayed without sending it")
+
+	// Add examples to help
+	replayCmd.SetUsageTemplate(usageTemplate)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"backfill-tool/internal"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var reportOutput string
+
+var reportCmd = &cobra.Command{
+	Use:   "report <metrics.json>",
+	Short: "Generate a self-contained HTML report from a metrics file",
+	Long: `Generate a single static HTML file from the metrics JSON saved by a run.
+
+The report contains per-item success rates, latency percentiles over time,
+the status code distribution, the most frequent error messages and a sample
+of failing rows. It has no external dependencies and can be shared as-is.`,
+	Example: `  # Writes metrics_20251103_114230.html next to the metrics file
+  backfill-tool report metrics_20251103_114230.json
+
+  # Custom output location
+  backfill-tool report metrics.json -o ./results/report.html`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		metricsFile := args[0]
+
+		output := reportOutput
+		if output == "" {
+			output = strings.TrimSuffix(metricsFile, filepath.Ext(metricsFile)) + ".html"
+		}
+
+		if err := internal.GenerateHTMLReport(metricsFile, output); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("📄 HTML report saved to: %s\n", output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "", "Path of the HTML file (default: metrics file name with .html)")
+}
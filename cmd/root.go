@@ -10,8 +10,11 @@ import (
 const version = "2.3.0"
 
 var (
-	verbose bool
-	quiet   bool
+	verbose         int
+	verboseFailures bool
+	quiet           bool
+	logFormat       string
+	logFile         string
 )
 
 var rootCmd = &cobra.Command{
@@ -78,7 +81,8 @@ var examplesCmd = &cobra.Command{
 	Long:  `Display comprehensive examples for common use cases with backfill-tool.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Backfill Tool - Usage Examples")
-		fmt.Println("================================\n")
+		fmt.Println("================================")
+		fmt.Println()
 
 		fmt.Println("1. SIMPLE POST REQUEST")
 		fmt.Println("   CSV file (users.csv):")
@@ -156,8 +160,11 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output with detailed logging")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Show every request: -v status and latency, -vv also headers and bodies, -vvv also responses and connection details")
+	rootCmd.PersistentFlags().BoolVar(&verboseFailures, "verbose-failures", false, "Limit verbose output to failed requests (implies -v)")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Quiet mode - suppress progress bars (useful for CI/CD)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Write a structured run log: text or json (default text when --log-file is set)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Append the structured run log to this file instead of stderr")
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(examplesCmd)
 }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"backfill-tool/internal"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var vaultFile string
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted local secrets vault",
+	Long: `Manage the passphrase-encrypted (age) vault used by {{secret:vault:NAME}} references.
+
+Collections, CSV files and --bearer-token can reference secrets instead of containing them:
+
+  {{secret:env:API_TOKEN}}          environment variable
+  {{secret:file:/run/secrets/token}} file contents (trailing newline removed)
+  {{secret:vault:api_token}}         entry of the encrypted vault
+
+References are resolved once when a run starts and their values are masked in all output.
+The passphrase is read from BACKFILL_VAULT_PASSPHRASE or prompted for on the terminal.`,
+	Example: `  # Store a secret (the value is prompted for, or read from stdin when piped)
+  backfill-tool secrets set api_token
+  vault read -field=token secret/api | backfill-tool secrets set api_token
+
+  # List stored names and remove one
+  backfill-tool secrets list
+  backfill-tool secrets rm api_token`,
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Add or replace a secret in the vault",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if strings.ContainsAny(name, "{}") || strings.TrimSpace(name) != name || name == "" {
+			exitWithError(fmt.Errorf("invalid secret name %q", name))
+		}
+
+		// Never take the value from the command line, where it would end up in shell history
+		value, err := readSecretValue(name)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		_, statErr := os.Stat(vaultFile)
+		passphrase, err := internal.ReadPassphrase("Vault passphrase: ", os.IsNotExist(statErr))
+		if err != nil {
+			exitWithError(err)
+		}
+		secrets, err := internal.LoadVault(vaultFile, passphrase)
+		if err != nil {
+			exitWithError(err)
+		}
+		secrets[name] = value
+		if err := internal.SaveVault(vaultFile, passphrase, secrets); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("🔒 Stored %s in %s\n", name, vaultFile)
+	},
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names stored in the vault",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase, err := internal.ReadPassphrase("Vault passphrase: ", false)
+		if err != nil {
+			exitWithError(err)
+		}
+		secrets, err := internal.LoadVault(vaultFile, passphrase)
+		if err != nil {
+			exitWithError(err)
+		}
+		for _, name := range internal.SecretNames(secrets) {
+			fmt.Println(name)
+		}
+	},
+}
+
+var secretsRemoveCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a secret from the vault",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase, err := internal.ReadPassphrase("Vault passphrase: ", false)
+		if err != nil {
+			exitWithError(err)
+		}
+		secrets, err := internal.LoadVault(vaultFile, passphrase)
+		if err != nil {
+			exitWithError(err)
+		}
+		if _, ok := secrets[args[0]]; !ok {
+			exitWithError(fmt.Errorf("%s is not in vault %s", args[0], vaultFile))
+		}
+		delete(secrets, args[0])
+		if err := internal.SaveVault(vaultFile, passphrase, secrets); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("🗑️  Removed %s from %s\n", args[0], vaultFile)
+	},
+}
+
+// readSecretValue prompts for a value without echo, or reads piped stdin
+func readSecretValue(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("error reading secret from stdin: %v", err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		if value == "" {
+			return "", fmt.Errorf("no secret value on stdin")
+		}
+		return value, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret: %v", err)
+	}
+	if len(value) == 0 {
+		return "", fmt.Errorf("secret value must not be empty")
+	}
+	return string(value), nil
+}
+
+// exitWithError prints an error and exits with status 1
+func exitWithError(err error) {
+	fmt.Println(err)
+	os.Exit(1)
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsSetCmd, secretsListCmd, secretsRemoveCmd)
+	secretsCmd.PersistentFlags().StringVar(&vaultFile, "vault", internal.DefaultVaultPath(), "Path of the encrypted vault file (env: BACKFILL_VAULT)")
+}
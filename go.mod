@@ -1,9 +1,17 @@
 module backfill-tool
 
-go 1.21
+go 1.24
+
+require (
+	filippo.io/age v1.2.1
+	github.com/spf13/cobra v1.10.1
+	github.com/spf13/pflag v1.0.10
+	golang.org/x/term v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
-	github.com/spf13/pflag v1.0.10 // indirect
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
 )
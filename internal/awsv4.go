@@ -0,0 +1,323 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	awsV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsV4TimeFormat = "20060102T150405Z"
+	awsV4DateFormat = "20060102"
+)
+
+// awsCredentials is an AWS access key pair with an optional session token
+type awsCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// awsV4Params holds the rendered settings of Postman's awsv4 auth export
+type awsV4Params struct {
+	awsCredentials
+	Region  string
+	Service string
+}
+
+// extractAWSv4 reads awsv4 settings in object or array format, renders template
+// variables and fills missing credentials from defaults (see defaultAWSCredentials).
+func extractAWSv4(raw json.RawMessage, csvData map[string]string, defaults func() (awsCredentials, error)) (awsV4Params, error) {
+	values := extractAuthParams(raw)
+	render := func(key string) string {
+		return replaceTemplateVariables(values[key], csvData)
+	}
+
+	params := awsV4Params{
+		awsCredentials: awsCredentials{
+			AccessKey:    render("accessKey"),
+			SecretKey:    render("secretKey"),
+			SessionToken: render("sessionToken"),
+		},
+		Region:  render("region"),
+		Service: render("service"),
+	}
+
+	if params.AccessKey == "" || params.SecretKey == "" {
+		creds, err := defaults()
+		if err != nil {
+			return params, err
+		}
+		params.awsCredentials = creds
+	}
+	if params.Region == "" {
+		params.Region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	}
+
+	if params.Region == "" || params.Service == "" {
+		return params, fmt.Errorf("awsv4 auth requires a region and a service")
+	}
+	return params, nil
+}
+
+// defaultAWSCredentials reads credentials from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY,
+// falling back to the AWS_PROFILE (or "default") profile of the shared credentials file.
+// A run reads them once, through batchRunner.awsCredentials.
+func defaultAWSCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKey != "" && creds.SecretKey != "" {
+		return creds, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return creds, fmt.Errorf("no AWS credentials found")
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := firstNonEmpty(os.Getenv("AWS_PROFILE"), "default")
+
+	creds, err := readAWSCredentialsFile(path, profile)
+	if err != nil {
+		return creds, fmt.Errorf("no AWS credentials in auth, environment or %s: %v", path, err)
+	}
+	return creds, nil
+}
+
+// readAWSCredentialsFile parses one profile of an INI style credentials file
+func readAWSCredentialsFile(path, profile string) (awsCredentials, error) {
+	var creds awsCredentials
+
+	file, err := os.Open(path)
+	if err != nil {
+		return creds, err
+	}
+	defer file.Close()
+
+	inProfile := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+		if !inProfile {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKey = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return creds, err
+	}
+
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return creds, fmt.Errorf("profile %q not found or incomplete", profile)
+	}
+	return creds, nil
+}
+
+// signAWSv4 adds a Signature Version 4 Authorization header to req.
+// It must run after all headers and the body are final, since both are signed.
+func signAWSv4(req *http.Request, body []byte, params awsV4Params, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(awsV4TimeFormat)
+	date := now.Format(awsV4DateFormat)
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if params.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", params.SessionToken)
+	}
+	if params.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := awsCanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL, params.Service),
+		awsCanonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, params.Region, params.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		awsV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+params.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, params.Region)
+	signingKey = hmacSHA256(signingKey, params.Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsV4Algorithm, params.AccessKey, scope, signedHeaders, signature))
+}
+
+// awsCanonicalURI encodes each segment of the path. Every service except S3
+// also expects the path normalized: no empty, "." or ".." segments.
+func awsCanonicalURI(u *url.URL, service string) string {
+	if u.Path == "" || u.Path == "/" {
+		return "/"
+	}
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/")[1:] {
+		if service != "s3" {
+			switch segment {
+			case "", ".":
+				continue
+			case "..":
+				if len(segments) > 0 {
+					segments = segments[:len(segments)-1]
+				}
+				continue
+			}
+		}
+		segments = append(segments, awsURIEncode(segment))
+	}
+	uri := "/" + strings.Join(segments, "/")
+	if service != "s3" && strings.HasSuffix(u.Path, "/") && uri != "/" {
+		uri += "/"
+	}
+	return uri
+}
+
+// awsCanonicalQuery encodes the query parameters as sent and sorts them by
+// encoded name, then value. The raw query is parsed by hand because
+// url.Values decodes "+" to a space, which is not what the server sees.
+func awsCanonicalQuery(u *url.URL) string {
+	type param struct{ key, value string }
+	var params []param
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		params = append(params, param{awsURIEncode(awsQueryUnescape(key)), awsURIEncode(awsQueryUnescape(value))})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].key != params[j].key {
+			return params[i].key < params[j].key
+		}
+		return params[i].value < params[j].value
+	})
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.key + "=" + p.value
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsQueryUnescape decodes percent escapes only, keeping "+" as is; a malformed
+// escape is signed as sent
+func awsQueryUnescape(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// awsCanonicalHeaders returns the canonical header block and the signed header list.
+// All headers set on the request are signed, plus Host.
+func awsCanonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string][]string{}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = values
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers["host"] = []string{host}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		values := make([]string, len(headers[name]))
+		for i, value := range headers[name] {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		fmt.Fprintf(&b, "%s:%s\n", name, strings.Join(values, ","))
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// awsURIEncode percent-encodes everything except RFC 3986 unreserved characters
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credentials, scope and time shared by the AWS Signature Version 4 test suite
+// (aws-sig-v4-test-suite)
+var (
+	awsSuiteParams = awsV4Params{
+		awsCredentials: awsCredentials{
+			AccessKey: "AKIDEXAMPLE",
+			SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		},
+		Region:  "us-east-1",
+		Service: "service",
+	}
+	awsSuiteTime  = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	awsSuiteToken = "AQoDYXdzEPT//////////wEXAMPLEtc764bNrC9SAPBSM22wDOk4x4HIZ8j4FZTwdQWLWsKWHGBuFqwAeMicRXmxfpSPfIeoIYRqTflfKD8YUuwthAx7mSEI/qkPpKPi/kMcGdQrmGdeehM4IC1NtBmUpp2wUE8phUZampKsburEDy0KPkyQDYwT7WZ0wq5VSXDvp75YU9HFvlRd8Tx6q6fE8YQcHNVXAkiY9q6d+xo0rKwT38xVqr7ZD0u0iPPkUL64lIZbqBAz+scqKmlzm8FDrypNC9Yjc8fPOLn9FX9KSYvKTr4rvx3iSIlTJabIQwj2ICCR/oLxBA=="
+)
+
+func TestSignAWSv4TestSuite(t *testing.T) {
+	const unreserved = "-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		header        map[string]string
+		body          string
+		sessionToken  string // Signed with the request
+		tokenAfter    bool   // Token header added after signing, so not signed
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			method:        "GET",
+			path:          "/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-vanilla-empty-query-key",
+			method:        "GET",
+			path:          "/?Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb",
+		},
+		{
+			name:          "get-vanilla-query-order-key",
+			method:        "GET",
+			path:          "/?Param2=value2&Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:          "get-vanilla-query-unreserved",
+			method:        "GET",
+			path:          "/?" + unreserved + "=" + unreserved,
+			signedHeaders: "host;x-amz-date",
+			signature:     "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197",
+		},
+		{
+			name:          "get-utf8",
+			method:        "GET",
+			path:          "/ሴ",
+			signedHeaders: "host;x-amz-date",
+			signature:     "8318018e0b0f223aa2bbf98705b62bb787dc9c0e678f255a891fd03141be5d85",
+		},
+		{
+			name:          "post-vanilla",
+			method:        "POST",
+			path:          "/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:          "post-x-www-form-urlencoded",
+			method:        "POST",
+			path:          "/",
+			header:        map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:          "Param1=value1",
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:          "post-x-www-form-urlencoded-parameters",
+			method:        "POST",
+			path:          "/",
+			header:        map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf8"},
+			body:          "Param1=value1",
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "1a72ec8f64bd914b0e42e42607c7fbce7fb2c7465f63e3092b3b0d39fa77a6fe",
+		},
+		{
+			name:          "post-sts-header-before",
+			method:        "POST",
+			path:          "/",
+			sessionToken:  awsSuiteToken,
+			signedHeaders: "host;x-amz-date;x-amz-security-token",
+			signature:     "85d96828115b5dc0cfc3bd16ad9e210dd772bbebba041836c64533a82be05ead",
+		},
+		{
+			name:          "post-sts-header-after",
+			method:        "POST",
+			path:          "/",
+			tokenAfter:    true,
+			signedHeaders: "host;x-amz-date",
+			signature:     "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com"+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			params := awsSuiteParams
+			params.SessionToken = tt.sessionToken
+
+			signAWSv4(req, []byte(tt.body), params, awsSuiteTime)
+			if tt.tokenAfter {
+				req.Header.Set("X-Amz-Security-Token", awsSuiteToken)
+			}
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization\n got: %s\nwant: %s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q, want 20150830T123600Z", got)
+			}
+		})
+	}
+}
+
+func TestAWSCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		rawQuery string
+		want     string
+	}{
+		{"", ""},
+		{"b=2&a=2&a=1", "a=1&a=2&b=2"},
+		{"a-b=1&a=1", "a=1&a-b=1"}, // Sorted by name, then value, not by the joined pair
+		{"q=a+b", "q=a%2Bb"},       // A literal "+" is sent as is, not as a space
+		{"q=a%20b&flag", "flag=&q=a%20b"},
+		{"key=%E1%88%B4", "key=%E1%88%B4"},
+		{"path=a/b", "path=a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawQuery, func(t *testing.T) {
+			if got := awsCanonicalQuery(&url.URL{RawQuery: tt.rawQuery}); got != tt.want {
+				t.Errorf("awsCanonicalQuery(%q) = %q, want %q", tt.rawQuery, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSCanonicalURI(t *testing.T) {
+	tests := []struct {
+		path    string
+		service string
+		want    string
+	}{
+		{"", "service", "/"},
+		{"/example space/", "service", "/example%20space/"},
+		{"//example//", "service", "/example/"},
+		{"/example/..", "service", "/"},
+		{"/./example", "service", "/example"},
+		{"/bucket//a b/../key", "s3", "/bucket//a%20b/../key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service+tt.path, func(t *testing.T) {
+			if got := awsCanonicalURI(&url.URL{Path: tt.path}, tt.service); got != tt.want {
+				t.Errorf("awsCanonicalURI(%q, %q) = %q, want %q", tt.path, tt.service, got, tt.want)
+			}
+		})
+	}
+}
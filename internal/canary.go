@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Canary decision modes
+const (
+	CanaryDecisionAuto   = "auto"   // Proceed when the thresholds pass, abort otherwise
+	CanaryDecisionPrompt = "prompt" // Show the results and ask on the terminal
+)
+
+// CanaryReport is the canary phase section of the metrics file
+type CanaryReport struct {
+	RowsPerItem       int     `json:"rows_per_item"`
+	Random            bool    `json:"random"`
+	Seed              uint64  `json:"seed,omitempty"`
+	MinSuccessRatePct float64 `json:"min_success_rate_pct"`
+	MaxP95Ms          int64   `json:"max_p95_ms,omitempty"`
+	Decision          string  `json:"decision"`
+	Aborted           bool    `json:"aborted"`
+
+	Items []CanaryItemReport `json:"items"`
+}
+
+// CanaryItemReport holds the canary metrics of one item and the decision taken
+type CanaryItemReport struct {
+	ItemMetricsReport
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"` // Thresholds that were missed
+	Outcome  string   `json:"outcome"`            // "proceeded" or "aborted"
+}
+
+// canaryPhase runs the first (or random) rows of every item before the rest
+// and decides from the results whether the run continues
+type canaryPhase struct {
+	rows       int
+	random     bool
+	seed       uint64
+	minSuccess float64 // Percent
+	maxP95     time.Duration
+	prompt     bool
+	quiet      bool
+	stdin      *bufio.Reader
+	report     *CanaryReport
+	mu         sync.Mutex // Items running in parallel decide one at a time
+}
+
+// newCanaryPhase returns nil when the run has no canary
+func newCanaryPhase(config RunConfig) (*canaryPhase, error) {
+	if config.CanaryRows <= 0 {
+		return nil, nil
+	}
+	if config.CanaryMinSuccess < 0 || config.CanaryMinSuccess > 100 {
+		return nil, fmt.Errorf("canary minimum success rate must be between 0 and 100")
+	}
+
+	c := &canaryPhase{
+		rows:       config.CanaryRows,
+		random:     config.CanaryRandom,
+		minSuccess: config.CanaryMinSuccess,
+		maxP95:     config.CanaryMaxP95,
+		quiet:      config.Quiet,
+	}
+	switch config.CanaryDecision {
+	case "", CanaryDecisionAuto:
+	case CanaryDecisionPrompt:
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("--canary-decision prompt needs an interactive terminal")
+		}
+		c.prompt = true
+		c.stdin = bufio.NewReader(os.Stdin)
+	default:
+		return nil, fmt.Errorf("unknown canary decision %q (use auto or prompt)", config.CanaryDecision)
+	}
+	if c.random {
+		c.seed = uint64(config.SampleSeed)
+		if c.seed == 0 {
+			c.seed = uint64(rand.Int64())
+		}
+	}
+
+	c.report = &CanaryReport{
+		RowsPerItem:       c.rows,
+		Random:            c.random,
+		Seed:              c.seed,
+		MinSuccessRatePct: c.minSuccess,
+		MaxP95Ms:          c.maxP95.Milliseconds(),
+		Decision:          CanaryDecisionAuto,
+		Items:             []CanaryItemReport{},
+	}
+	if c.prompt {
+		c.report.Decision = CanaryDecisionPrompt
+	}
+	return c, nil
+}
+
+// split returns the canary records and the rest, both in file order. Random
+// canaries are the same rows for every item of a run.
+func (c *canaryPhase) split(records []csvRecord) (canary, rest []csvRecord) {
+	if c.rows >= len(records) {
+		return records, nil
+	}
+	picked := make([]bool, len(records))
+	if c.random {
+		rng := rand.New(rand.NewPCG(c.seed, c.seed))
+		for _, i := range rng.Perm(len(records))[:c.rows] {
+			picked[i] = true
+		}
+	} else {
+		for i := 0; i < c.rows; i++ {
+			picked[i] = true
+		}
+	}
+	for i, record := range records {
+		if picked[i] {
+			canary = append(canary, record)
+		} else {
+			rest = append(rest, record)
+		}
+	}
+	return canary, rest
+}
+
+// decide evaluates the canary metrics of an item against the thresholds,
+// prompts if configured, records the outcome and reports whether to proceed
+func (c *canaryPhase) decide(metrics RequestMetrics, remaining int, indent string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item := buildItemReport(metrics)
+
+	var failures []string
+	if item.SuccessRatePct < c.minSuccess {
+		failures = append(failures, fmt.Sprintf("success rate %.1f%% is below %.1f%%", item.SuccessRatePct, c.minSuccess))
+	}
+	if p95 := time.Duration(item.Timing.P95Ms) * time.Millisecond; c.maxP95 > 0 && p95 > c.maxP95 {
+		failures = append(failures, fmt.Sprintf("p95 latency %v is above %v", p95, c.maxP95))
+	}
+	passed := len(failures) == 0
+
+	status := colorize(colorGreen, "passed")
+	if !passed {
+		status = colorize(colorRed, "failed: "+strings.Join(failures, ", "))
+	}
+	if !c.quiet || c.prompt || !passed {
+		screen.Printf("%s   🐤 Canary %d/%d succeeded (%.1f%%), p95 %dms - %s\n", indent,
+			item.Successful, item.TotalRequests, item.SuccessRatePct, item.Timing.P95Ms, status)
+	}
+
+	proceed := passed
+	if c.prompt && remaining > 0 {
+		proceed = c.confirm(fmt.Sprintf("%s   Send the remaining %d rows of %s?", indent, remaining, metrics.Name), passed)
+	}
+
+	outcome := "proceeded"
+	if !proceed {
+		outcome = "aborted"
+		c.report.Aborted = true
+		screen.Printf("%s   %s\n", indent, colorize(colorRed, "🛑 Aborting the run after the canary of "+metrics.Name))
+	}
+	level := slog.LevelInfo
+	if !proceed {
+		level = slog.LevelWarn
+	}
+	logger.Log(context.Background(), level, "canary finished", "item", metrics.Name, "passed", passed,
+		"success_rate_pct", item.SuccessRatePct, "p95_ms", item.Timing.P95Ms, "outcome", outcome)
+	c.report.Items = append(c.report.Items, CanaryItemReport{
+		ItemMetricsReport: item,
+		Passed:            passed,
+		Failures:          failures,
+		Outcome:           outcome,
+	})
+	return proceed
+}
+
+// confirm asks a yes/no question; an empty answer takes the default
+func (c *canaryPhase) confirm(question string, defaultYes bool) bool {
+	choices := "[y/N]"
+	if defaultYes {
+		choices = "[Y/n]"
+	}
+	// The dashboard of items still running would redraw over the question
+	screen.pause()
+	defer screen.resume()
+	for {
+		screen.Printf("%s %s ", question, choices)
+		answer, err := c.stdin.ReadString('\n')
+		if err != nil {
+			screen.Println()
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return defaultYes
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
@@ -0,0 +1,724 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the schema of a run configuration file (backfill.yaml)
+type fileConfig struct {
+	Collection  string    `yaml:"collection"`
+	CSV         string    `yaml:"csv"`
+	Threads     int       `yaml:"threads"`
+	SerializeBy []string  `yaml:"serialize_by"`
+	BatchSize   int       `yaml:"batch_size"`
+	DryRun      bool      `yaml:"dry_run"`
+	Verbose     verbosity `yaml:"verbose"`
+	Quiet       bool      `yaml:"quiet"`
+
+	VerboseFailures bool `yaml:"verbose_failures"`
+
+	Variables     map[string]string `yaml:"variables"`
+	VariableFiles []string          `yaml:"variable_files"`
+
+	Items struct {
+		Include []string `yaml:"include"` // Requests
+		Folders []string `yaml:"folders"`
+		Exclude []string `yaml:"exclude"` // Requests or folders
+	} `yaml:"items"`
+
+	Parallel struct {
+		Items        int    `yaml:"items"`
+		Scope        string `yaml:"scope"`
+		WorkerBudget string `yaml:"worker_budget"`
+	} `yaml:"parallel"`
+
+	Dedupe struct {
+		By   []string `yaml:"by"`
+		Keep string   `yaml:"keep"`
+		Mode string   `yaml:"mode"`
+	} `yaml:"dedupe"`
+
+	Rows struct {
+		Range  string   `yaml:"range"`
+		Where  []string `yaml:"where"`
+		Sample string   `yaml:"sample"`
+		Seed   int64    `yaml:"seed"`
+		Skip   int      `yaml:"skip"`
+		Limit  int      `yaml:"limit"`
+	} `yaml:"rows"`
+
+	Canary struct {
+		Rows           int           `yaml:"rows"`
+		Random         bool          `yaml:"random"`
+		MinSuccessRate float64       `yaml:"min_success_rate"`
+		MaxP95         time.Duration `yaml:"max_p95"`
+		Decision       string        `yaml:"decision"`
+	} `yaml:"canary"`
+
+	RunID string `yaml:"run_id"`
+
+	Idempotency struct {
+		Enabled bool     `yaml:"enabled"`
+		Header  string   `yaml:"header"`
+		Columns []string `yaml:"columns"`
+	} `yaml:"idempotency"`
+
+	Auth struct {
+		BearerToken string                 `yaml:"bearer_token"`
+		Override    map[string]interface{} `yaml:"override"` // Postman auth object
+	} `yaml:"auth"`
+
+	Retry struct {
+		MaxRetries int           `yaml:"max_retries"`
+		Backoff    time.Duration `yaml:"backoff"`
+		MaxBackoff time.Duration `yaml:"max_backoff"`
+		OnStatus   []int         `yaml:"on_status"`
+	} `yaml:"retry"`
+
+	RateLimit struct {
+		RequestsPerSecond float64 `yaml:"requests_per_second"`
+		Burst             int     `yaml:"burst"`
+	} `yaml:"rate_limit"`
+
+	HTTP struct {
+		Timeout               time.Duration `yaml:"timeout"`
+		DialTimeout           time.Duration `yaml:"dial_timeout"`
+		TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`
+		ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+		IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`
+		MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"`
+		DisableKeepAlives     bool          `yaml:"disable_keep_alives"`
+		Version               string        `yaml:"version"`
+	} `yaml:"http"`
+
+	TLS struct {
+		CACert             string   `yaml:"ca_cert"`
+		ClientCert         string   `yaml:"client_cert"`
+		ClientKey          string   `yaml:"client_key"`
+		HostClientCerts    []string `yaml:"host_client_certs"`
+		MinVersion         string   `yaml:"min_version"`
+		ServerName         string   `yaml:"server_name"`
+		InsecureSkipVerify bool     `yaml:"insecure_skip_verify"`
+	} `yaml:"tls"`
+
+	Proxy struct {
+		URL     string   `yaml:"url"`
+		NoProxy []string `yaml:"no_proxy"`
+	} `yaml:"proxy"`
+
+	Outputs struct {
+		Dir         string `yaml:"dir"`
+		MetricsFile string `yaml:"metrics_file"`
+		HTMLReport  string `yaml:"html_report"`
+		JUnit       string `yaml:"junit"`
+
+		RecordRequests bool `yaml:"record_requests"`
+	} `yaml:"outputs"`
+
+	Redact struct {
+		Patterns []string `yaml:"patterns"`
+		Columns  []string `yaml:"columns"`
+	} `yaml:"redact"`
+
+	Secrets struct {
+		Vault string `yaml:"vault"`
+	} `yaml:"secrets"`
+
+	Logging struct {
+		Format string `yaml:"format"`
+		File   string `yaml:"file"`
+	} `yaml:"logging"`
+
+	Tracing struct {
+		OTLPEndpoint string `yaml:"otlp_endpoint"`
+		OTLPFile     string `yaml:"otlp_file"`
+		ServiceName  string `yaml:"service_name"`
+	} `yaml:"tracing"`
+}
+
+// verbosity is the verbose level of a config file: true for level 1 or a level number
+type verbosity int
+
+func (v *verbosity) UnmarshalYAML(node *yaml.Node) error {
+	var on bool
+	if err := node.Decode(&on); err == nil {
+		*v = 0
+		if on {
+			*v = VerboseRequests
+		}
+		return nil
+	}
+	var level int
+	if err := node.Decode(&level); err != nil {
+		return fmt.Errorf("line %d: verbose must be true, false or a level from 0 to %d", node.Line, VerboseBodies)
+	}
+	*v = verbosity(level)
+	return nil
+}
+
+// envRefPattern matches ${VAR}, ${VAR:-default} and the $${ escape
+var envRefPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// unknownFieldPattern matches yaml.v3's error for keys missing from the schema
+var unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+// LoadRunConfig reads a YAML run configuration file. Environment variables are
+// interpolated first, then the file is checked against the schema. Every
+// problem found is reported with its line number. Relative paths in the file
+// are resolved against the file's directory.
+func LoadRunConfig(configPath string) (RunConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return RunConfig{}, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	data, problems := interpolateEnv(data)
+	if len(problems) > 0 {
+		return RunConfig{}, configError(configPath, problems)
+	}
+
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, message := range typeErr.Errors {
+				problems = append(problems, unknownFieldPattern.ReplaceAllString(message, `unknown key "$1"`))
+			}
+			return RunConfig{}, configError(configPath, problems)
+		}
+		return RunConfig{}, configError(configPath, []string{strings.TrimPrefix(err.Error(), "yaml: ")})
+	}
+
+	var root yaml.Node
+	yaml.Unmarshal(data, &root)
+	if problems := validateFileConfig(file, &root); len(problems) > 0 {
+		return RunConfig{}, configError(configPath, problems)
+	}
+
+	config := file.runConfig()
+	if file.Auth.Override != nil {
+		overrideJSON, err := json.Marshal(file.Auth.Override)
+		if err != nil {
+			return RunConfig{}, configError(configPath, []string{fmt.Sprintf("line %d: auth.override: %v", nodeLine(&root, "auth", "override"), err)})
+		}
+		config.AuthOverride = &PostmanAuth{}
+		if err := json.Unmarshal(overrideJSON, config.AuthOverride); err != nil {
+			return RunConfig{}, configError(configPath, []string{fmt.Sprintf("line %d: auth.override: %v", nodeLine(&root, "auth", "override"), err)})
+		}
+	}
+	resolveConfigPaths(&config, filepath.Dir(configPath))
+	config.fileKeys = map[string]bool{}
+	collectKeys(findNode(&root), "", config.fileKeys)
+	return config, nil
+}
+
+// Has reports whether the config file the RunConfig was loaded from sets a key,
+// given as a dotted path like "retry.max_retries". A key set to an explicit zero
+// value counts; one left empty (null) does not.
+func (c RunConfig) Has(key string) bool {
+	return c.fileKeys[key]
+}
+
+// collectKeys records the dotted path of every key with a value in a mapping and its nested mappings
+func collectKeys(node *yaml.Node, prefix string, keys map[string]bool) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := prefix+node.Content[i].Value, node.Content[i+1]
+		if value.Tag == "!!null" {
+			continue
+		}
+		keys[key] = true
+		collectKeys(value, key+".", keys)
+	}
+}
+
+func configError(configPath string, problems []string) error {
+	return fmt.Errorf("invalid config file %s:\n  %s", configPath, strings.Join(problems, "\n  "))
+}
+
+// interpolateEnv replaces ${VAR} and ${VAR:-default} with environment values.
+// ${VAR:-default} also applies the default when VAR is empty; $${ yields a
+// literal ${. Comment lines are left alone.
+func interpolateEnv(data []byte) ([]byte, []string) {
+	var problems []string
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		lines[i] = envRefPattern.ReplaceAllStringFunc(line, func(match string) string {
+			if match == "$${" {
+				return "${"
+			}
+			groups := envRefPattern.FindStringSubmatch(match)
+			value, set := os.LookupEnv(groups[1])
+			if strings.Contains(match, ":-") && value == "" {
+				return groups[2]
+			}
+			if !set {
+				problems = append(problems, fmt.Sprintf("line %d: environment variable %s is not set (use ${%s:-default} for a fallback)", i+1, groups[1], groups[1]))
+			}
+			return value
+		})
+	}
+	return []byte(strings.Join(lines, "\n")), problems
+}
+
+// validateFileConfig checks values the YAML types cannot express
+func validateFileConfig(file fileConfig, root *yaml.Node) []string {
+	var problems []string
+	report := func(line int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+	}
+	present := func(keys ...string) bool {
+		return findNode(root, keys...) != nil
+	}
+
+	if present("threads") && file.Threads <= 0 {
+		report(nodeLine(root, "threads"), "threads must be greater than 0")
+	}
+	if file.BatchSize < 0 {
+		report(nodeLine(root, "batch_size"), "batch_size must not be negative")
+	}
+	if file.Verbose < 0 || file.Verbose > VerboseBodies {
+		report(nodeLine(root, "verbose"), "verbose must be true, false or a level from 0 to %d", VerboseBodies)
+	}
+
+	for _, key := range []string{"include", "folders", "exclude"} {
+		if list := findNode(root, "items", key); list != nil {
+			for _, pattern := range list.Content {
+				if _, err := path.Match(pattern.Value, ""); err != nil {
+					report(pattern.Line, "invalid item pattern %q: %v", pattern.Value, err)
+				}
+			}
+		}
+	}
+
+	if file.Parallel.Items < 0 {
+		report(nodeLine(root, "parallel", "items"), "parallel.items must not be negative")
+	}
+	switch file.Parallel.Scope {
+	case "", ParallelScopeItem, ParallelScopeFolder:
+	default:
+		report(nodeLine(root, "parallel", "scope"), "parallel.scope must be item or folder")
+	}
+	switch file.Parallel.WorkerBudget {
+	case "", WorkerBudgetShared, WorkerBudgetPerItem:
+	default:
+		report(nodeLine(root, "parallel", "worker_budget"), "parallel.worker_budget must be shared or per-item")
+	}
+
+	switch file.Logging.Format {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		report(nodeLine(root, "logging", "format"), "logging.format must be text or json")
+	}
+
+	switch file.Dedupe.Keep {
+	case "", DedupeKeepFirst, DedupeKeepLast:
+	default:
+		report(nodeLine(root, "dedupe", "keep"), "dedupe.keep must be first or last")
+	}
+	switch file.Dedupe.Mode {
+	case "", DedupeModeMemory, DedupeModeDisk, DedupeModeApproximate:
+	default:
+		report(nodeLine(root, "dedupe", "mode"), "dedupe.mode must be memory, disk or approximate")
+	}
+
+	if file.Rows.Range != "" {
+		if _, _, err := parseRowRange(file.Rows.Range); err != nil {
+			report(nodeLine(root, "rows", "range"), "rows.range: %v", err)
+		}
+	}
+	if file.Rows.Sample != "" {
+		if _, err := parseSampleRate(file.Rows.Sample); err != nil {
+			report(nodeLine(root, "rows", "sample"), "rows.sample: %v", err)
+		}
+	}
+	if file.Rows.Skip < 0 {
+		report(nodeLine(root, "rows", "skip"), "rows.skip must not be negative")
+	}
+	if file.Rows.Limit < 0 {
+		report(nodeLine(root, "rows", "limit"), "rows.limit must not be negative")
+	}
+
+	if file.Canary.Rows < 0 {
+		report(nodeLine(root, "canary", "rows"), "canary.rows must not be negative")
+	}
+	if rate := file.Canary.MinSuccessRate; rate < 0 || rate > 100 {
+		report(nodeLine(root, "canary", "min_success_rate"), "canary.min_success_rate must be between 0 and 100")
+	}
+	if file.Canary.MaxP95 < 0 {
+		report(nodeLine(root, "canary", "max_p95"), "canary.max_p95 must not be negative")
+	}
+	switch file.Canary.Decision {
+	case "", CanaryDecisionAuto, CanaryDecisionPrompt:
+	default:
+		report(nodeLine(root, "canary", "decision"), "canary.decision must be auto or prompt")
+	}
+
+	if file.Auth.Override != nil {
+		authType, _ := file.Auth.Override["type"].(string)
+		if !supportedAuthTypes[authType] {
+			report(nodeLine(root, "auth", "override"), "auth.override.type must be one of noauth, bearer, apikey, basic, oauth2, awsv4, digest, hawk")
+		}
+	}
+
+	if file.Retry.MaxRetries < 0 {
+		report(nodeLine(root, "retry", "max_retries"), "retry.max_retries must not be negative")
+	}
+	if file.Retry.Backoff < 0 {
+		report(nodeLine(root, "retry", "backoff"), "retry.backoff must not be negative")
+	}
+	if file.Retry.MaxBackoff < 0 {
+		report(nodeLine(root, "retry", "max_backoff"), "retry.max_backoff must not be negative")
+	}
+	if statuses := findNode(root, "retry", "on_status"); statuses != nil {
+		for i, status := range file.Retry.OnStatus {
+			if status < 100 || status > 599 {
+				report(statuses.Content[i].Line, "retry.on_status: %d is not an HTTP status code", status)
+			}
+		}
+	}
+
+	if file.RateLimit.RequestsPerSecond < 0 {
+		report(nodeLine(root, "rate_limit", "requests_per_second"), "rate_limit.requests_per_second must not be negative")
+	}
+	if file.RateLimit.Burst < 0 {
+		report(nodeLine(root, "rate_limit", "burst"), "rate_limit.burst must not be negative")
+	}
+
+	switch file.HTTP.Version {
+	case "", "1.1", "2", "h2c":
+	default:
+		report(nodeLine(root, "http", "version"), "http.version must be 1.1, 2 or h2c")
+	}
+	for _, key := range []string{"timeout", "dial_timeout", "tls_handshake_timeout", "response_header_timeout", "idle_conn_timeout"} {
+		if node := findNode(root, "http", key); node != nil && strings.HasPrefix(node.Value, "-") {
+			report(node.Line, "http.%s must not be negative", key)
+		}
+	}
+
+	if _, ok := tlsVersions[file.TLS.MinVersion]; file.TLS.MinVersion != "" && !ok {
+		report(nodeLine(root, "tls", "min_version"), "tls.min_version must be 1.0, 1.1, 1.2 or 1.3")
+	}
+	if (file.TLS.ClientCert == "") != (file.TLS.ClientKey == "") {
+		report(nodeLine(root, "tls"), "tls.client_cert and tls.client_key must be set together")
+	}
+
+	if patterns := findNode(root, "redact", "patterns"); patterns != nil {
+		for _, pattern := range patterns.Content {
+			if _, err := regexp.Compile(pattern.Value); err != nil {
+				report(pattern.Line, "invalid redact pattern %q: %v", pattern.Value, err)
+			}
+		}
+	}
+	return problems
+}
+
+// findNode returns the value node at a key path of the document, or nil
+func findNode(root *yaml.Node, keys ...string) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// nodeLine returns the line of the value at a key path, or 0 when it is absent
+func nodeLine(root *yaml.Node, keys ...string) int {
+	if node := findNode(root, keys...); node != nil {
+		return node.Line
+	}
+	return 0
+}
+
+// runConfig maps the file schema onto RunConfig
+func (file fileConfig) runConfig() RunConfig {
+	return RunConfig{
+		BatchSize:   file.BatchSize,
+		Threads:     file.Threads,
+		SerializeBy: file.SerializeBy,
+
+		ParallelItems: file.Parallel.Items,
+		ParallelScope: file.Parallel.Scope,
+		WorkerBudget:  file.Parallel.WorkerBudget,
+		Collection:    file.Collection,
+		CSV:           file.CSV,
+		MetricsFile:   file.Outputs.MetricsFile,
+		HTMLReport:    file.Outputs.HTMLReport,
+		JUnitFile:     file.Outputs.JUnit,
+		Verbose:       int(file.Verbose),
+		Quiet:         file.Quiet,
+		BearerToken:   file.Auth.BearerToken,
+
+		VerboseFailures: file.VerboseFailures,
+
+		LogFormat: file.Logging.Format,
+		LogFile:   file.Logging.File,
+
+		RequestTimeout:        file.HTTP.Timeout,
+		DialTimeout:           file.HTTP.DialTimeout,
+		TLSHandshakeTimeout:   file.HTTP.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: file.HTTP.ResponseHeaderTimeout,
+		IdleConnTimeout:       file.HTTP.IdleConnTimeout,
+		MaxIdleConnsPerHost:   file.HTTP.MaxIdleConnsPerHost,
+		DisableKeepAlives:     file.HTTP.DisableKeepAlives,
+		HTTPVersion:           file.HTTP.Version,
+
+		CACertFile:         file.TLS.CACert,
+		ClientCertFile:     file.TLS.ClientCert,
+		ClientKeyFile:      file.TLS.ClientKey,
+		HostClientCerts:    file.TLS.HostClientCerts,
+		TLSMinVersion:      file.TLS.MinVersion,
+		TLSServerName:      file.TLS.ServerName,
+		InsecureSkipVerify: file.TLS.InsecureSkipVerify,
+
+		Proxy:   file.Proxy.URL,
+		NoProxy: file.Proxy.NoProxy,
+		DryRun:  file.DryRun,
+
+		RedactPatterns: file.Redact.Patterns,
+		RedactColumns:  file.Redact.Columns,
+
+		VaultFile: file.Secrets.Vault,
+
+		Retries:         file.Retry.MaxRetries,
+		RetryBackoff:    file.Retry.Backoff,
+		RetryMaxBackoff: file.Retry.MaxBackoff,
+		RetryOn:         file.Retry.OnStatus,
+		RateLimit:       file.RateLimit.RequestsPerSecond,
+		RateLimitBurst:  file.RateLimit.Burst,
+
+		Variables:     file.Variables,
+		VariableFiles: file.VariableFiles,
+		Items:         file.Items.Include,
+		Folders:       file.Items.Folders,
+		ExcludeItems:  file.Items.Exclude,
+
+		DedupeBy:   file.Dedupe.By,
+		DedupeKeep: file.Dedupe.Keep,
+		DedupeMode: file.Dedupe.Mode,
+
+		Rows:       file.Rows.Range,
+		Where:      file.Rows.Where,
+		Sample:     file.Rows.Sample,
+		SampleSeed: file.Rows.Seed,
+		Skip:       file.Rows.Skip,
+		Limit:      file.Rows.Limit,
+
+		CanaryRows:       file.Canary.Rows,
+		CanaryRandom:     file.Canary.Random,
+		CanaryMinSuccess: file.Canary.MinSuccessRate,
+		CanaryMaxP95:     file.Canary.MaxP95,
+		CanaryDecision:   file.Canary.Decision,
+
+		RunID:              file.RunID,
+		IdempotencyKeys:    file.Idempotency.Enabled || file.Idempotency.Header != "" || len(file.Idempotency.Columns) > 0,
+		IdempotencyHeader:  file.Idempotency.Header,
+		IdempotencyColumns: file.Idempotency.Columns,
+
+		OutputDir:      file.Outputs.Dir,
+		RecordRequests: file.Outputs.RecordRequests,
+
+		TraceEndpoint:    file.Tracing.OTLPEndpoint,
+		TraceFile:        file.Tracing.OTLPFile,
+		TraceServiceName: file.Tracing.ServiceName,
+	}
+}
+
+// resolveConfigPaths makes relative paths of a config file relative to its directory
+func resolveConfigPaths(config *RunConfig, dir string) {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	for _, p := range []*string{
+		&config.Collection, &config.CSV,
+		&config.MetricsFile, &config.HTMLReport, &config.JUnitFile, &config.OutputDir,
+		&config.CACertFile, &config.ClientCertFile, &config.ClientKeyFile,
+		&config.VaultFile, &config.TraceFile, &config.LogFile,
+	} {
+		*p = resolve(*p)
+	}
+	for i, file := range config.VariableFiles {
+		config.VariableFiles[i] = resolve(file)
+	}
+	for i, spec := range config.HostClientCerts {
+		host, files, ok := strings.Cut(spec, "=")
+		certFile, keyFile, ok2 := strings.Cut(files, ":")
+		if ok && ok2 {
+			config.HostClientCerts[i] = host + "=" + resolve(certFile) + ":" + resolve(keyFile)
+		}
+	}
+}
+
+// ConfigTemplate is the commented configuration file written by "config init"
+const ConfigTemplate = `# backfill-tool run configuration
+#
+# Run with:   backfill-tool run --config backfill.yaml
+# Flags given on the command line override values from this file.
+# ${VAR} and ${VAR:-default} are replaced with environment variables ($${ for a literal ${).
+# Relative paths are resolved against the directory of this file.
+
+collection: collection.json   # Postman collection (v2.1 JSON export)
+csv: data.csv                 # One request per row; columns fill {{placeholders}}
+threads: 10                   # Concurrent workers
+# serialize_by: [account_id]  # Rows sharing these values are sent one at a time, in CSV order
+# dry_run: false              # Print rendered requests without sending them
+# verbose: false              # true or 1: a line per request; 2: headers and bodies; 3: responses and connections
+# verbose_failures: false     # Verbose output for failed requests only
+# quiet: false
+
+# Static template variables; CSV columns with the same name win
+# variables:
+#   baseUrl: https://api.example.com
+#   tenant: ${TENANT:-default}
+
+# Postman environment exports, flat JSON/YAML objects or .env files, later files win
+# variable_files:
+#   - staging.postman_environment.json
+
+# Names, "Folder/Item" paths or globs (see 'backfill-tool list'); default runs everything
+# items:
+#   include: ["Orders/Create"]   # Requests
+#   folders: ["Users"]           # Everything inside these folders
+#   exclude: ["Users/Delete*"]   # Requests or folders
+
+# Run several collection items at the same time (default: one after another)
+# parallel:
+#   items: 1
+#   scope: item                # item, or folder: top-level folders in parallel, their requests in order
+#   worker_budget: shared      # shared: threads is the limit for all items; per-item: threads workers each
+
+# Send one row per key; duplicates are saved to duplicates_<time>.csv in the output directory
+# dedupe:
+#   by: [order_id]
+#   keep: first                # or last
+#   mode: memory               # memory, disk (exact, for files too large to index in memory) or approximate
+
+# Row filters, applied in this order; where expressions are checked against the CSV header at start
+# rows:
+#   range: "100:200"           # 1-based data rows, inclusive; either end may be omitted
+#   where: ['region == "eu"', 'amount >= 100']
+#   sample: 1%                 # Or a fraction like 0.01
+#   seed: 42                   # Repeats a sample (default: random, printed)
+#   skip: 0
+#   limit: 0
+
+# Canary: send some rows of each item first and continue only if they pass
+# canary:
+#   rows: 0                    # 0 disables the canary
+#   random: false              # Random rows (seeded by rows.seed) instead of the first ones
+#   min_success_rate: 100      # Percent
+#   max_p95: 0s                # 0 = latency not checked
+#   decision: auto             # auto or prompt
+
+# run_id: ""                   # Recorded in the metrics; idempotency keys derive from it (default: generated)
+
+# Idempotency keys: the same key for every retry and re-run of a row
+# idempotency:
+#   enabled: false
+#   header: Idempotency-Key
+#   columns: [payment_id]      # Identify rows by these columns instead of their row number
+
+# auth:
+#   bearer_token: "{{secret:env:API_TOKEN}}"   # Replaces all collection auth
+#   override:                                 # Postman auth object replacing collection and request auth
+#     type: apikey
+#     apikey:
+#       - {key: key, value: X-API-Key}
+#       - {key: value, value: "{{secret:vault:api_key}}"}
+#       - {key: in, value: header}
+
+# retry:
+#   max_retries: 0             # Extra attempts for transport errors and on_status codes
+#   backoff: 500ms             # First delay, doubled per attempt with jitter
+#   max_backoff: 30s           # Also caps Retry-After
+#   on_status: [429, 502, 503, 504]
+
+# rate_limit:
+#   requests_per_second: 0     # Across all workers, 0 = unlimited
+#   burst: 1
+
+# http:
+#   timeout: 30s
+#   dial_timeout: 10s
+#   tls_handshake_timeout: 10s
+#   response_header_timeout: 0s
+#   idle_conn_timeout: 90s
+#   max_idle_conns_per_host: 0 # Default: number of workers
+#   disable_keep_alives: false
+#   version: ""                # 1.1, 2 or h2c; default negotiates via TLS
+
+# tls:
+#   ca_cert: ca.pem
+#   client_cert: client.pem
+#   client_key: client-key.pem
+#   host_client_certs: ["api.partner.com=partner.pem:partner-key.pem"]
+#   min_version: "1.2"
+#   server_name: ""
+#   insecure_skip_verify: false
+
+# proxy:
+#   url: http://proxy.internal:3128
+#   no_proxy: [".internal", "10.0.0.0/8"]
+
+# outputs:
+#   dir: results               # Failed request CSVs and the default metrics file
+#   metrics_file: ""           # Default: <dir>/metrics_<timestamp>.json
+#   html_report: ""
+#   junit: ""
+#   record_requests: false     # Save the masked requests of failures for replay
+
+# redact:
+#   patterns: ['ssn=(\d+)']
+#   columns: [email]
+
+# secrets:
+#   vault: vault.age           # Default: ~/.backfill-tool/vault.age or $BACKFILL_VAULT
+
+# logging:
+#   format: json               # text or json; the run log is off unless format or file is set
+#   file: backfill.log         # Appended to; default: stderr
+
+# tracing:
+#   otlp_endpoint: http://localhost:4318
+#   otlp_file: ""
+#   service_name: backfill-tool
+`
@@ -0,0 +1,375 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	dashboardRefresh  = 200 * time.Millisecond // Redraws of the live dashboard on a terminal
+	plainProgressRate = 10 * time.Second       // Progress lines when stdout is not a terminal
+	dashboardBarWidth = 24
+	dashboardTopErrs  = 3 // Error kinds shown per item
+)
+
+// colorEnabled turns ANSI colors on for terminals, unless NO_COLOR is set
+// (https://no-color.org) or the terminal cannot show them
+var colorEnabled = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && term.IsTerminal(int(os.Stdout.Fd()))
+
+// screen is the human readable output of a run. Everything a run prints goes
+// through it so output lands above the live dashboard instead of inside it.
+var screen = newConsole(os.Stdout)
+
+// console prints run output and, while items are in progress, a dashboard with
+// one line per item. On a terminal the dashboard stays at the bottom and is
+// redrawn in place; otherwise it is printed as plain lines every
+// plainProgressRate.
+type console struct {
+	mu       sync.Mutex
+	w        io.Writer
+	fd       int
+	live     bool               // Redraw in place (stdout is a capable terminal)
+	trackers []*ProgressTracker // Items in progress, in start order
+	drawn    int                // Dashboard lines currently on screen
+	paused   int                // Nesting depth of pause; nothing is drawn while paused
+	stop     chan struct{}      // Stops the refresh loop; nil while it is not running
+}
+
+func newConsole(f *os.File) *console {
+	fd := int(f.Fd())
+	return &console{w: f, fd: fd, live: os.Getenv("TERM") != "dumb" && term.IsTerminal(fd)}
+}
+
+// Printf prints above the dashboard
+func (c *console) Printf(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+	fmt.Fprintf(c.w, format, args...)
+	c.draw()
+}
+
+// Println prints a line above the dashboard
+func (c *console) Println(args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+	fmt.Fprintln(c.w, args...)
+	c.draw()
+}
+
+// writer returns a writer for another stream of the terminal, such as stderr,
+// that prints above the dashboard
+func (c *console) writer(w io.Writer) io.Writer {
+	return consoleWriter{console: c, w: w}
+}
+
+type consoleWriter struct {
+	console *console
+	w       io.Writer
+}
+
+func (cw consoleWriter) Write(p []byte) (int, error) {
+	cw.console.mu.Lock()
+	defer cw.console.mu.Unlock()
+	cw.console.clear()
+	n, err := cw.w.Write(p)
+	cw.console.draw()
+	return n, err
+}
+
+// pause removes the dashboard until resume, for prompts that read a line
+func (c *console) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+	c.paused++
+}
+
+func (c *console) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused--
+	c.draw()
+}
+
+// start adds an item to the dashboard
+func (c *console) start(t *ProgressTracker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+	c.trackers = append(c.trackers, t)
+	c.draw()
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+		go c.refresh(c.stop)
+	}
+}
+
+// finish removes an item from the dashboard and prints its final state
+func (c *console) finish(t *ProgressTracker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+	for i, tracker := range c.trackers {
+		if tracker == t {
+			c.trackers = append(c.trackers[:i], c.trackers[i+1:]...)
+			break
+		}
+	}
+	if c.live {
+		fmt.Fprintln(c.w, c.fit(t.line()))
+	} else {
+		fmt.Fprintln(c.w, t.plainLine())
+	}
+	c.draw()
+	if len(c.trackers) == 0 && c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+func (c *console) refresh(stop chan struct{}) {
+	interval := dashboardRefresh
+	if !c.live {
+		interval = plainProgressRate
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.live {
+				c.clear()
+				c.draw()
+			} else if c.paused == 0 {
+				for _, t := range c.trackers {
+					fmt.Fprintln(c.w, t.plainLine())
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// clear erases the dashboard; the cursor ends where it started
+func (c *console) clear() {
+	if c.drawn > 0 {
+		fmt.Fprintf(c.w, "\033[%dA\033[J", c.drawn)
+		c.drawn = 0
+	}
+}
+
+// draw prints the dashboard below the cursor
+func (c *console) draw() {
+	if !c.live || c.paused > 0 {
+		return
+	}
+	for _, t := range c.trackers {
+		fmt.Fprintln(c.w, c.fit(t.line()))
+	}
+	c.drawn = len(c.trackers)
+}
+
+var ansiSequence = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// fit drops trailing sections of a dashboard line until it fits the terminal
+// width, so lines never wrap and the redraw stays in place
+func (c *console) fit(sections []string) string {
+	width, _, err := term.GetSize(c.fd)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	for {
+		line := strings.Join(sections, " | ")
+		if len(sections) == 1 || utf8.RuneCountInString(ansiSequence.ReplaceAllString(line, "")) < width {
+			return line
+		}
+		sections = sections[:len(sections)-1]
+	}
+}
+
+// ProgressTracker follows the requests of one item for the dashboard
+type ProgressTracker struct {
+	total     int64
+	started   int64
+	current   int64
+	success   int64
+	failure   int64
+	retries   int64
+	totalTime int64 // Nanoseconds of response time over all finished requests
+	startTime time.Time
+	quiet     bool
+
+	mu          sync.Mutex
+	errors      map[string]int64 // Failures per kind: status code, "timeout", "network" or "auth"
+	description string
+}
+
+// NewProgressTracker creates a progress tracker and shows it on the dashboard
+func NewProgressTracker(total int, description string, quiet bool) *ProgressTracker {
+	p := &ProgressTracker{
+		total:       int64(total),
+		startTime:   time.Now(),
+		quiet:       quiet,
+		errors:      map[string]int64{},
+		description: description,
+	}
+	if !quiet {
+		screen.start(p)
+	}
+	return p
+}
+
+// Started counts a request a worker begins sending
+func (p *ProgressTracker) Started() {
+	atomic.AddInt64(&p.started, 1)
+}
+
+// Update counts a finished request
+func (p *ProgressTracker) Update(result RequestResult) {
+	atomic.AddInt64(&p.current, 1)
+	atomic.AddInt64(&p.retries, int64(result.Retries))
+	atomic.AddInt64(&p.totalTime, int64(result.ResponseTime))
+	if result.Success {
+		atomic.AddInt64(&p.success, 1)
+		return
+	}
+	atomic.AddInt64(&p.failure, 1)
+	p.mu.Lock()
+	p.errors[errorKind(result)]++
+	p.mu.Unlock()
+}
+
+// Finish removes the tracker from the dashboard, leaving its final line
+func (p *ProgressTracker) Finish() {
+	if !p.quiet {
+		screen.finish(p)
+	}
+}
+
+// errorKind groups failures for the dashboard
+func errorKind(result RequestResult) string {
+	switch {
+	case result.AuthError:
+		return "auth"
+	case result.StatusCode != 0:
+		return fmt.Sprintf("%d", result.StatusCode)
+	case strings.Contains(strings.ToLower(result.Error), "timeout"):
+		return "timeout"
+	}
+	return "network"
+}
+
+// progressStats is a snapshot of a tracker
+type progressStats struct {
+	current, success, failure, inFlight, retries int64
+	percent                                      float64
+	rate                                         float64 // Requests per second
+	avg                                          time.Duration
+	eta                                          time.Duration
+	errors                                       string // Most frequent error kinds, e.g. "500×8 timeout×2"
+}
+
+func (p *ProgressTracker) stats() progressStats {
+	s := progressStats{
+		current: atomic.LoadInt64(&p.current),
+		success: atomic.LoadInt64(&p.success),
+		failure: atomic.LoadInt64(&p.failure),
+		retries: atomic.LoadInt64(&p.retries),
+	}
+	s.inFlight = atomic.LoadInt64(&p.started) - s.current
+	if s.current > 0 {
+		s.avg = time.Duration(atomic.LoadInt64(&p.totalTime) / s.current)
+	}
+	if p.total > 0 {
+		s.percent = float64(s.current) / float64(p.total) * 100
+	}
+	if elapsed := time.Since(p.startTime).Seconds(); elapsed > 0 {
+		s.rate = float64(s.current) / elapsed
+	}
+	if s.rate > 0 {
+		s.eta = time.Duration(float64(p.total-s.current) / s.rate * float64(time.Second))
+	}
+
+	p.mu.Lock()
+	kinds := make([]string, 0, len(p.errors))
+	for kind := range p.errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if p.errors[kinds[i]] != p.errors[kinds[j]] {
+			return p.errors[kinds[i]] > p.errors[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	if len(kinds) > dashboardTopErrs {
+		kinds = kinds[:dashboardTopErrs]
+	}
+	for i, kind := range kinds {
+		kinds[i] = fmt.Sprintf("%s×%d", kind, p.errors[kind])
+	}
+	p.mu.Unlock()
+	s.errors = strings.Join(kinds, " ")
+	return s
+}
+
+// line renders the dashboard line of a tracker as sections, least important last
+func (p *ProgressTracker) line() []string {
+	s := p.stats()
+	filled := int(float64(dashboardBarWidth) * s.percent / 100)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", dashboardBarWidth-filled)
+
+	sections := []string{
+		fmt.Sprintf("%s [%s] %d/%d (%.1f%%)", colorize(colorBold, p.description), bar, s.current, p.total, s.percent),
+		fmt.Sprintf("%s %s", colorize(colorGreen, fmt.Sprintf("✓%d", s.success)), colorize(colorRed, fmt.Sprintf("✗%d", s.failure))),
+		fmt.Sprintf("%.1f/s", s.rate),
+		fmt.Sprintf("Avg: %dms", s.avg.Milliseconds()),
+		"ETA " + formatDuration(s.eta),
+		fmt.Sprintf("%d in flight", s.inFlight),
+	}
+	if s.retries > 0 {
+		sections = append(sections, fmt.Sprintf("%d retries", s.retries))
+	}
+	if s.errors != "" {
+		sections = append(sections, colorize(colorRed, s.errors))
+	}
+	return sections
+}
+
+// plainLine renders a tracker as a log line for output that is not a terminal
+func (p *ProgressTracker) plainLine() string {
+	s := p.stats()
+	line := fmt.Sprintf("[progress] %s: %d/%d (%.1f%%), %d ok, %d failed, %.1f req/s, avg %dms, %d in flight, %d retries, ETA %s",
+		p.description, s.current, p.total, s.percent, s.success, s.failure, s.rate, s.avg.Milliseconds(), s.inFlight, s.retries, formatDuration(s.eta))
+	if s.errors != "" {
+		line += ", errors: " + s.errors
+	}
+	return line
+}
+
+// formatDuration formats duration for display
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	minutes := int(d.Minutes())
+	seconds := int(d.Seconds()) % 60
+	return fmt.Sprintf("%dm%ds", minutes, seconds)
+}
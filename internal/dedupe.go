@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Dedupe modes
+const (
+	DedupeModeMemory      = "memory"      // Exact; holds a hash of every key in memory
+	DedupeModeDisk        = "disk"        // Exact; partitions key hashes into temporary files
+	DedupeModeApproximate = "approximate" // Fixed size Bloom filter; may drop a few unique rows
+)
+
+// Which of the rows sharing a key is sent
+const (
+	DedupeKeepFirst = "first"
+	DedupeKeepLast  = "last"
+)
+
+const (
+	// dedupePartitions is the number of temporary files the disk mode spreads keys
+	// over; only one partition's keys are in memory at a time
+	dedupePartitions = 64
+
+	// dedupeFalsePositiveRate sizes the Bloom filter of the approximate mode
+	dedupeFalsePositiveRate = 0.001
+)
+
+// DedupeReport records the deduplication of a run in the metrics file
+type DedupeReport struct {
+	Columns    []string `json:"columns"`
+	Keep       string   `json:"keep"`
+	Mode       string   `json:"mode"`
+	CSVRows    int      `json:"csv_rows"`
+	Duplicates int      `json:"duplicates"`
+	Kept       int      `json:"kept"`
+	ReportFile string   `json:"report_file,omitempty"` // CSV of the removed rows
+}
+
+// rowDeduper drops CSV rows whose key columns repeat an earlier (or, keeping the
+// last, a later) row before any request is sent
+type rowDeduper struct {
+	columns  []string
+	keepLast bool
+	mode     string
+	report   DedupeReport
+}
+
+// dedupeKey is a hash of a row's key column values
+type dedupeKey [16]byte
+
+// dedupeDuplicate is a removed row and the row that was kept in its place
+type dedupeDuplicate struct {
+	position int // Index of the row in the input file (0 = first data row)
+	keptRow  int // Data row number of the kept row; 0 when unknown (approximate mode)
+}
+
+// newRowDeduper returns nil when no key columns are configured. The key columns
+// are checked against the CSV headers when the file is read.
+func newRowDeduper(config RunConfig) (*rowDeduper, error) {
+	if len(config.DedupeBy) == 0 {
+		return nil, nil
+	}
+
+	d := &rowDeduper{columns: config.DedupeBy, mode: config.DedupeMode}
+	switch config.DedupeKeep {
+	case "", DedupeKeepFirst:
+	case DedupeKeepLast:
+		d.keepLast = true
+	default:
+		return nil, fmt.Errorf("unknown --dedupe-keep %q (use first or last)", config.DedupeKeep)
+	}
+	switch d.mode {
+	case "":
+		d.mode = DedupeModeMemory
+	case DedupeModeMemory, DedupeModeDisk, DedupeModeApproximate:
+	default:
+		return nil, fmt.Errorf("unknown --dedupe-mode %q (use memory, disk or approximate)", config.DedupeMode)
+	}
+
+	d.report = DedupeReport{Columns: d.columns, Keep: DedupeKeepFirst, Mode: d.mode}
+	if d.keepLast {
+		d.report.Keep = DedupeKeepLast
+	}
+	return d, nil
+}
+
+// key hashes the key column values of a row; values are length prefixed so
+// "a,bc" and "ab,c" differ
+func (d *rowDeduper) key(row map[string]string) dedupeKey {
+	h := sha256.New()
+	for _, column := range d.columns {
+		value := row[column]
+		fmt.Fprintf(h, "%d:%s", len(value), value)
+	}
+	var key dedupeKey
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// read streams the CSV file twice and returns its headers, the rows that
+// remain, in file order, and the removed ones. The first pass spills the key of
+// every row to a temporary file and finds the duplicates from it; the second
+// keeps the other rows, so duplicate rows are never held in memory. resolve
+// prepares each row before its key is taken.
+func (d *rowDeduper) read(path string, resolve func(row map[string]string) error) ([]string, []csvRecord, []dedupeDuplicate, error) {
+	dir, err := os.MkdirTemp("", "backfill-dedupe-")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error creating dedupe spill directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := os.Create(filepath.Join(dir, "keys"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error creating dedupe spill file: %v", err)
+	}
+	defer file.Close()
+	keys := &dedupeKeyFile{file: file}
+	writer := bufio.NewWriter(file)
+	headers, err := streamCSV(path, func(row map[string]string) error {
+		if keys.rows == 0 {
+			for _, column := range d.columns {
+				if _, ok := row[column]; !ok {
+					return fmt.Errorf("dedupe column %q is not in the CSV file", column)
+				}
+			}
+		}
+		if err := resolve(row); err != nil {
+			return err
+		}
+		key := d.key(row)
+		if _, err := writer.Write(key[:]); err != nil {
+			return fmt.Errorf("error writing dedupe spill file: %v", err)
+		}
+		keys.rows++
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, nil, nil, fmt.Errorf("error writing dedupe spill file: %v", err)
+	}
+
+	var duplicates []dedupeDuplicate
+	switch d.mode {
+	case DedupeModeDisk:
+		duplicates, err = d.findOnDisk(dir, keys)
+	case DedupeModeApproximate:
+		duplicates, err = d.findApproximate(keys)
+	default:
+		duplicates, err = d.findInMemory(keys)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].position < duplicates[j].position })
+
+	kept := make([]csvRecord, 0, keys.rows-len(duplicates))
+	position, next := 0, 0
+	if _, err := streamCSV(path, func(row map[string]string) error {
+		index := position
+		position++
+		if next < len(duplicates) && duplicates[next].position == index {
+			next++
+			return nil
+		}
+		if err := resolve(row); err != nil {
+			return err
+		}
+		kept = append(kept, csvRecord{Index: index + 1, Data: row})
+		return nil
+	}); err != nil {
+		return nil, nil, nil, err
+	}
+
+	d.report.CSVRows = keys.rows
+	d.report.Duplicates = len(duplicates)
+	d.report.Kept = len(kept)
+	return headers, kept, duplicates, nil
+}
+
+// dedupeKeyFile holds the key of every CSV row, in file order, so the keys can
+// be read again from either end
+type dedupeKeyFile struct {
+	file *os.File
+	rows int
+}
+
+// each passes every key and its row position to fn, starting from the last row
+// when reverse is set. Keeping the last row of a key is keeping the first one
+// seen from the end.
+func (k *dedupeKeyFile) each(reverse bool, fn func(position int, key dedupeKey) error) error {
+	const keySize = len(dedupeKey{})
+	const chunkRows = 4096
+	buf := make([]byte, chunkRows*keySize)
+	for done := 0; done < k.rows; done += chunkRows {
+		n := min(chunkRows, k.rows-done)
+		first := done
+		if reverse {
+			first = k.rows - done - n
+		}
+		chunk := buf[:n*keySize]
+		if read, err := k.file.ReadAt(chunk, int64(first*keySize)); read < len(chunk) {
+			return fmt.Errorf("error reading dedupe spill file: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			j := i
+			if reverse {
+				j = n - 1 - i
+			}
+			var key dedupeKey
+			copy(key[:], chunk[j*keySize:])
+			if err := fn(first+j, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *rowDeduper) findInMemory(keys *dedupeKeyFile) ([]dedupeDuplicate, error) {
+	seen := make(map[dedupeKey]int)
+	var duplicates []dedupeDuplicate
+	err := keys.each(d.keepLast, func(position int, key dedupeKey) error {
+		if kept, ok := seen[key]; ok {
+			duplicates = append(duplicates, dedupeDuplicate{position: position, keptRow: kept + 1})
+			return nil
+		}
+		seen[key] = position
+		return nil
+	})
+	return duplicates, err
+}
+
+// findOnDisk writes every key with its position to one of dedupePartitions
+// temporary files in dir by hash, then finds the duplicates one partition at a
+// time. Keys of a partition keep their order, so the first one seen still wins.
+func (d *rowDeduper) findOnDisk(dir string, keys *dedupeKeyFile) ([]dedupeDuplicate, error) {
+	const entrySize = len(dedupeKey{}) + 8
+	files := make([]*os.File, dedupePartitions)
+	writers := make([]*bufio.Writer, dedupePartitions)
+	for i := range files {
+		var err error
+		if files[i], err = os.Create(filepath.Join(dir, strconv.Itoa(i))); err != nil {
+			return nil, fmt.Errorf("error creating dedupe spill file: %v", err)
+		}
+		defer files[i].Close()
+		writers[i] = bufio.NewWriter(files[i])
+	}
+
+	var entry [entrySize]byte
+	if err := keys.each(d.keepLast, func(position int, key dedupeKey) error {
+		copy(entry[:], key[:])
+		binary.LittleEndian.PutUint64(entry[len(key):], uint64(position))
+		if _, err := writers[int(key[0])%dedupePartitions].Write(entry[:]); err != nil {
+			return fmt.Errorf("error writing dedupe spill file: %v", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var duplicates []dedupeDuplicate
+	for i, file := range files {
+		if err := writers[i].Flush(); err != nil {
+			return nil, fmt.Errorf("error writing dedupe spill file: %v", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("error reading dedupe spill file: %v", err)
+		}
+
+		seen := make(map[dedupeKey]int)
+		reader := bufio.NewReader(file)
+		for {
+			if _, err := io.ReadFull(reader, entry[:]); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("error reading dedupe spill file: %v", err)
+			}
+			var key dedupeKey
+			copy(key[:], entry[:])
+			position := int(binary.LittleEndian.Uint64(entry[len(key):]))
+			if kept, ok := seen[key]; ok {
+				duplicates = append(duplicates, dedupeDuplicate{position: position, keptRow: kept + 1})
+				continue
+			}
+			seen[key] = position
+		}
+	}
+	return duplicates, nil
+}
+
+// findApproximate tracks keys in a Bloom filter sized for the row count. A false
+// positive removes a unique row, which shows up in the duplicates report
+// without a kept row.
+func (d *rowDeduper) findApproximate(keys *dedupeKeyFile) ([]dedupeDuplicate, error) {
+	filter := newBloomFilter(keys.rows, dedupeFalsePositiveRate)
+	var duplicates []dedupeDuplicate
+	err := keys.each(d.keepLast, func(position int, key dedupeKey) error {
+		if !filter.add(key) {
+			duplicates = append(duplicates, dedupeDuplicate{position: position})
+		}
+		return nil
+	})
+	return duplicates, err
+}
+
+// bloomFilter is a set of keys that can answer "maybe seen" or "never seen"
+type bloomFilter struct {
+	bits   []uint64
+	hashes int
+}
+
+func newBloomFilter(n int, falsePositiveRate float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	bits := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Round(bits / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{bits: make([]uint64, int(bits)/64+1), hashes: hashes}
+}
+
+// add inserts a key and reports whether it was (probably) new. The bit
+// positions come from the two halves of the key by double hashing.
+func (f *bloomFilter) add(key dedupeKey) bool {
+	h1 := binary.LittleEndian.Uint64(key[:8])
+	h2 := binary.LittleEndian.Uint64(key[8:]) | 1
+	size := uint64(len(f.bits) * 64)
+	added := false
+	for i := 0; i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			f.bits[bit/64] |= 1 << (bit % 64)
+			added = true
+		}
+	}
+	return added
+}
+
+// saveDuplicates reads the CSV file again and writes the removed rows, in the
+// file's column order, with their row number and the row kept in their place,
+// and returns the file name
+func saveDuplicates(path string, headers []string, duplicates []dedupeDuplicate, dir string, redactor *redactor) (string, error) {
+	filename := filepath.Join(dir, fmt.Sprintf("duplicates_%s.csv", time.Now().Format("20060102_150405")))
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(append(append([]string(nil), headers...), "_dedupe_row", "_dedupe_kept_row"))
+	position, next := 0, 0
+	if _, err := streamCSV(path, func(row map[string]string) error {
+		index := position
+		position++
+		if next >= len(duplicates) || duplicates[next].position != index {
+			return nil
+		}
+		duplicate := duplicates[next]
+		next++
+
+		data := redactor.Row(row)
+		record := make([]string, 0, len(headers)+2)
+		for _, header := range headers {
+			record = append(record, data[header])
+		}
+		keptRow := ""
+		if duplicate.keptRow > 0 {
+			keptRow = strconv.Itoa(duplicate.keptRow)
+		}
+		return writer.Write(append(record, strconv.Itoa(index+1), keptRow))
+	}); err != nil {
+		return "", err
+	}
+	writer.Flush()
+	return filename, writer.Error()
+}
@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestChallenge is a parsed WWW-Authenticate: Digest header
+type digestChallenge struct {
+	Realm     string
+	Nonce     string
+	Opaque    string
+	Algorithm string // MD5 (default), MD5-sess, SHA-256 or SHA-256-sess
+	QOP       string // Chosen qop: "auth", "auth-int" or "" for RFC 2069 servers
+}
+
+// digestEntry is the last challenge seen for one host and user. Later requests
+// answer it directly instead of paying for a 401 round-trip each time.
+type digestEntry struct {
+	mu         sync.Mutex
+	challenge  digestChallenge
+	nonceCount uint32
+}
+
+// digestCache shares digest challenges across workers
+type digestCache struct {
+	mu      sync.Mutex
+	entries map[string]*digestEntry
+}
+
+// newDigestCache creates an empty digest challenge cache
+func newDigestCache() *digestCache {
+	return &digestCache{entries: make(map[string]*digestEntry)}
+}
+
+// extractDigestAuth reads digest credentials in object or array format and renders template variables
+func extractDigestAuth(raw json.RawMessage, csvData map[string]string) (username, password string) {
+	params := extractAuthParams(raw)
+	return replaceTemplateVariables(params["username"], csvData), replaceTemplateVariables(params["password"], csvData)
+}
+
+func digestCacheKey(req *http.Request, username string) string {
+	return req.URL.Scheme + "://" + req.URL.Host + "\x00" + username
+}
+
+// Authorize answers the cached challenge for the request's host, if there is one.
+// It reports whether an Authorization header was set.
+func (c *digestCache) Authorize(req *http.Request, username, password string, body []byte) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[digestCacheKey(req, username)]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	entry.mu.Lock()
+	entry.nonceCount++
+	challenge, nc := entry.challenge, entry.nonceCount
+	entry.mu.Unlock()
+
+	req.Header.Set("Authorization", digestAuthorization(challenge, req.Method, req.URL.RequestURI(), username, password, body, nc, newCNonce()))
+	return true
+}
+
+// Store remembers a new challenge for the request's host, resetting the nonce count
+func (c *digestCache) Store(req *http.Request, username string, challenge digestChallenge) {
+	key := digestCacheKey(req, username)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &digestEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	if entry.challenge.Nonce != challenge.Nonce {
+		entry.challenge = challenge
+		entry.nonceCount = 0
+	}
+	entry.mu.Unlock()
+}
+
+// parseDigestChallenge finds a Digest challenge among WWW-Authenticate headers
+func parseDigestChallenge(headers []string) (digestChallenge, bool) {
+	for _, header := range headers {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		params := parseAuthParams(rest)
+		challenge := digestChallenge{
+			Realm:     params["realm"],
+			Nonce:     params["nonce"],
+			Opaque:    params["opaque"],
+			Algorithm: params["algorithm"],
+		}
+		if challenge.Algorithm == "" {
+			challenge.Algorithm = "MD5"
+		}
+		if _, ok := digestHash(challenge.Algorithm); !ok || challenge.Nonce == "" {
+			continue
+		}
+
+		// Prefer plain auth; auth-int also covers the body but fewer servers accept it
+		for _, qop := range strings.Split(params["qop"], ",") {
+			qop = strings.TrimSpace(qop)
+			if qop == "auth" {
+				challenge.QOP = qop
+				break
+			}
+			if qop == "auth-int" {
+				challenge.QOP = qop
+			}
+		}
+		return challenge, true
+	}
+	return digestChallenge{}, false
+}
+
+// parseAuthParams parses comma separated key=value or key="quoted value" pairs
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " ")
+
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[key] = value.String()
+	}
+	return params
+}
+
+// digestAuthorization computes the Authorization header value for a challenge (RFC 7616)
+func digestAuthorization(c digestChallenge, method, uri, username, password string, body []byte, nc uint32, cnonce string) string {
+	newHash, _ := digestHash(c.Algorithm)
+	h := func(parts ...string) string {
+		hasher := newHash()
+		hasher.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	ha1 := h(username, c.Realm, password)
+	if strings.HasSuffix(strings.ToLower(c.Algorithm), "-sess") {
+		ha1 = h(ha1, c.Nonce, cnonce)
+	}
+	ha2 := h(method, uri)
+	if c.QOP == "auth-int" {
+		ha2 = h(method, uri, h(string(body)))
+	}
+
+	ncValue := fmt.Sprintf("%08x", nc)
+	var response string
+	if c.QOP == "" {
+		response = h(ha1, c.Nonce, ha2)
+	} else {
+		response = h(ha1, c.Nonce, ncValue, cnonce, c.QOP, ha2)
+	}
+
+	fields := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, c.Realm),
+		fmt.Sprintf(`nonce="%s"`, c.Nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		"algorithm=" + c.Algorithm,
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if c.Opaque != "" {
+		fields = append(fields, fmt.Sprintf(`opaque="%s"`, c.Opaque))
+	}
+	if c.QOP != "" {
+		fields = append(fields, "qop="+c.QOP, "nc="+ncValue, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	return "Digest " + strings.Join(fields, ", ")
+}
+
+// digestHash returns the hash function of a digest algorithm
+func digestHash(algorithm string) (func() hash.Hash, bool) {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "MD5":
+		return md5.New, true
+	case "SHA-256":
+		return sha256.New, true
+	}
+	return nil, false
+}
+
+// newCNonce returns a random client nonce
+func newCNonce() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Transport defaults used when the corresponding RunConfig field is zero
+const (
+	defaultRequestTimeout      = 30 * time.Second
+	defaultDialTimeout         = 10 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// newHTTPClient builds the single client shared by all workers of a run.
+// The idle pool is sized to the worker count so every worker can keep its
+// connection open between requests instead of redialing.
+func newHTTPClient(config RunConfig, proxy proxyFunc) (*http.Client, error) {
+	protocols, err := transportProtocols(config.HTTPVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, hostCerts, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := durationOrDefault(config.RequestTimeout, defaultRequestTimeout)
+
+	maxIdlePerHost := config.MaxIdleConnsPerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = config.Threads
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   durationOrDefault(config.DialTimeout, defaultDialTimeout),
+		KeepAlive: defaultKeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdlePerHost * 4,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		IdleConnTimeout:       durationOrDefault(config.IdleConnTimeout, defaultIdleConnTimeout),
+		TLSHandshakeTimeout:   durationOrDefault(config.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     config.DisableKeepAlives,
+		Protocols:             protocols,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+	if len(hostCerts) > 0 {
+		client.Transport = newHostRoutingTransport(transport, hostCerts)
+	}
+	return client, nil
+}
+
+// transportProtocols maps the --http-version flag to the protocols the transport may use:
+//
+//	""    HTTP/1.1, or HTTP/2 when negotiated via TLS ALPN (default)
+//	"1.1" HTTP/1.1 only
+//	"2"   HTTP/2 over TLS only
+//	"h2c" HTTP/2 over cleartext TCP with prior knowledge
+func transportProtocols(version string) (*http.Protocols, error) {
+	protocols := &http.Protocols{}
+	switch version {
+	case "":
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case "1.1":
+		protocols.SetHTTP1(true)
+	case "2":
+		protocols.SetHTTP2(true)
+	case "h2c":
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unsupported HTTP version %q (use 1.1, 2 or h2c)", version)
+	}
+	return protocols, nil
+}
+
+// durationOrDefault returns d, or def when d is not set
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
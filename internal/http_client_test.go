@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkWorkers is the number of concurrent senders, like --threads
+const benchmarkWorkers = 16
+
+// BenchmarkSharedClient compares the run's shared, tuned client with a new
+// client per request against a local server, reporting connection reuse from
+// the per-item metrics a run collects.
+func BenchmarkSharedClient(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	config := RunConfig{Threads: benchmarkWorkers}
+
+	b.Run("shared", func(b *testing.B) {
+		client, err := newHTTPClient(config, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer client.CloseIdleConnections()
+		benchmarkSend(b, server.URL, config, client)
+	})
+
+	b.Run("per-request", func(b *testing.B) {
+		benchmarkSend(b, server.URL, config, nil)
+	})
+}
+
+// benchmarkSend sends b.N requests from benchmarkWorkers goroutines through
+// batchRunner.send, using shared or, when it is nil, a new client per request
+func benchmarkSend(b *testing.B, url string, config RunConfig, shared *http.Client) {
+	const body = `{"id":"1","name":"n"}`
+	var mu sync.Mutex
+	metrics := RequestMetrics{Name: "benchmark", MinTime: time.Hour, StartTime: time.Now()}
+
+	b.SetParallelism(max(1, benchmarkWorkers/runtime.GOMAXPROCS(0)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			client := shared
+			if client == nil {
+				var err error
+				if client, err = newHTTPClient(config, nil); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+			runner := &batchRunner{config: config, client: client}
+			req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			result := runner.send(PostmanItem{}, csvRecord{}, req, body, nil, nil, RequestResult{Timestamp: time.Now(), URL: url})
+			if !result.Success {
+				b.Error(result.Error)
+				return
+			}
+			if shared == nil {
+				client.CloseIdleConnections()
+			}
+			mu.Lock()
+			metrics.recordResult(result)
+			mu.Unlock()
+		}
+	})
+	b.StopTimer()
+
+	metrics.EndTime = time.Now()
+	report := buildItemReport(metrics)
+	b.ReportMetric(report.Connections.ReuseRatePct, "reuse%")
+	b.ReportMetric(float64(report.Connections.New)/float64(b.N), "dials/op")
+}
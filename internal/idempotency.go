@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultIdempotencyHeader is the header idempotency keys are sent in unless configured otherwise
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// idempotencyKeyColumn holds the key of each row in failed request CSVs, and
+// idempotencyItemColumn the item it was sent to. A row reuses the key for that
+// item instead of deriving a new one, so re-running a failed request CSV
+// repeats the original keys; other items of the collection get keys of their own.
+const (
+	idempotencyKeyColumn  = "_idempotency_key"
+	idempotencyItemColumn = "_idempotency_item"
+)
+
+// idempotencyKeys derives a stable key per row and item. Retries of a row send
+// the same key, and so does a later run with the same run ID and input.
+type idempotencyKeys struct {
+	header  string
+	runID   string
+	columns []string // Identify rows by these columns instead of their row number
+}
+
+// newIdempotencyKeys returns nil when idempotency keys are disabled. columns are
+// the CSV headers the identity columns must be among.
+func newIdempotencyKeys(config RunConfig, runID string, columns map[string]bool) (*idempotencyKeys, error) {
+	if !config.IdempotencyKeys {
+		return nil, nil
+	}
+	for _, column := range config.IdempotencyColumns {
+		if !columns[column] {
+			return nil, fmt.Errorf("idempotency key column %q is not in the CSV file", column)
+		}
+	}
+	header := config.IdempotencyHeader
+	if header == "" {
+		header = DefaultIdempotencyHeader
+	}
+	return &idempotencyKeys{header: header, runID: runID, columns: config.IdempotencyColumns}, nil
+}
+
+// Key returns the idempotency key of a row for an item, formatted as a UUID
+// (version 8) so APIs that require UUID keys accept it
+func (k *idempotencyKeys) Key(itemName string, record csvRecord) string {
+	if key := record.Data[idempotencyKeyColumn]; key != "" && record.Data[idempotencyItemColumn] == itemName {
+		return key
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", k.runID, itemName)
+	if len(k.columns) == 0 {
+		h.Write([]byte(strconv.Itoa(record.Index)))
+	}
+	for _, column := range k.columns {
+		fmt.Fprintf(h, "%s\x00", record.Data[column])
+	}
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x80 // Version 8
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 9562 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// idempotencyKeyOf returns the key a failed request was sent with and its item,
+// or the key and item its row carried when the run did not send keys
+func idempotencyKeyOf(result RequestResult) (key, item string) {
+	if result.IdempotencyKey != "" {
+		return result.IdempotencyKey, result.RequestName
+	}
+	return result.CSVData[idempotencyKeyColumn], result.CSVData[idempotencyItemColumn]
+}
+
+// newRunID returns an ID for a run that has none configured
+func newRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%x", time.Now().Format("20060102-150405"), suffix)
+}
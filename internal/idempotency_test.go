@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+)
+
+func TestIdempotencyKeyRecordedForOneItem(t *testing.T) {
+	keys, err := newIdempotencyKeys(RunConfig{IdempotencyKeys: true}, "run-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const recorded = "0b5f0c1e-6a3d-8c2f-9e41-5d7a2b3c4d5e"
+	record := csvRecord{Index: 1, Data: map[string]string{
+		"id":                  "7",
+		idempotencyKeyColumn:  recorded,
+		idempotencyItemColumn: "Create Order",
+	}}
+
+	if got := keys.Key("Create Order", record); got != recorded {
+		t.Errorf("Key(Create Order) = %q, want the recorded key %q", got, recorded)
+	}
+	other := keys.Key("Notify Customer", record)
+	if other == recorded {
+		t.Errorf("Key(Notify Customer) reused the key recorded for Create Order")
+	}
+	if want := keys.Key("Notify Customer", csvRecord{Index: 1, Data: map[string]string{"id": "7"}}); other != want {
+		t.Errorf("Key(Notify Customer) = %q, want the derived key %q", other, want)
+	}
+}
+
+func TestSaveFailedRequestsRecordsKeyItem(t *testing.T) {
+	dir := t.TempDir()
+	failed := []RequestResult{
+		{RequestName: "Create Order", RowIndex: 1, CSVData: map[string]string{"id": "7"}, IdempotencyKey: "key-create"},
+		// A re-run row failing for another item keeps the item its key belongs to
+		{RequestName: "Create Order", RowIndex: 2, CSVData: map[string]string{
+			"id":                  "8",
+			idempotencyKeyColumn:  "key-notify",
+			idempotencyItemColumn: "Notify Customer",
+		}},
+	}
+
+	filename := saveFailedRequests(failed, "Create Order", dir, false)
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	column := map[string]int{}
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for i, want := range [][2]string{{"key-create", "Create Order"}, {"key-notify", "Notify Customer"}} {
+		row := rows[i+1]
+		if got := [2]string{row[column[idempotencyKeyColumn]], row[column[idempotencyItemColumn]]}; got != want {
+			t.Errorf("row %d key and item = %v, want %v", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite maps to one collection item
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is either a failed row or the aggregate of all successful rows
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes why a row failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// saveJUnitReport writes run results as JUnit XML: one test suite per collection
+// item, one failing test case per failed row, one failing case for the rows a
+// canary abort left unsent and one passing case for the rest.
+func saveJUnitReport(runMetrics *RunMetrics, filename string) error {
+	report := junitTestSuites{
+		Name: runMetrics.CollectionName,
+		Time: runMetrics.EndTime.Sub(runMetrics.StartTime).Seconds(),
+	}
+
+	for _, item := range runMetrics.ItemMetrics {
+		suite := junitTestSuite{
+			Name:      item.Name,
+			Failures:  len(item.FailedRequests),
+			Time:      item.EndTime.Sub(item.StartTime).Seconds(),
+			Timestamp: item.StartTime.Format("2006-01-02T15:04:05"),
+		}
+
+		if item.SuccessCount > 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("%d successful rows", item.SuccessCount),
+				Classname: item.Name,
+			})
+		}
+
+		// Workers finish out of order; list failures in CSV order
+		failed := append([]RequestResult(nil), item.FailedRequests...)
+		sort.Slice(failed, func(i, j int) bool { return failed[i].RowIndex < failed[j].RowIndex })
+
+		for _, fr := range failed {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("row %d (%s)", fr.RowIndex, fr.RecordInfo),
+				Classname: item.Name,
+				Time:      fr.ResponseTime.Seconds(),
+				Failure: &junitFailure{
+					Message: cleanErrorMessage(fr.Error),
+					Type:    junitFailureType(fr),
+					Text:    junitFailureText(fr),
+				},
+			})
+		}
+
+		if item.Skipped > 0 {
+			suite.Failures++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("%d rows not sent", item.Skipped),
+				Classname: item.Name,
+				Failure: &junitFailure{
+					Message: "Run aborted by a canary",
+					Type:    "Aborted",
+					Text:    fmt.Sprintf("%d rows of %s were not sent because a canary stopped the run", item.Skipped, item.Name),
+				},
+			})
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+// junitFailureType classifies a failure by status code
+func junitFailureType(fr RequestResult) string {
+	if fr.StatusCode == 0 {
+		return "RequestError"
+	}
+	return fmt.Sprintf("HTTP%d", fr.StatusCode)
+}
+
+// junitFailureText lists the request and CSV data of a failed row
+func junitFailureText(fr RequestResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", fr.Method, fr.URL)
+	fmt.Fprintf(&b, "status: %d, response time: %dms\n", fr.StatusCode, fr.ResponseTime.Milliseconds())
+
+	columns := make([]string, 0, len(fr.CSVData))
+	for column := range fr.CSVData {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		fmt.Fprintf(&b, "%s=%s\n", column, fr.CSVData[column])
+	}
+
+	b.WriteString(fr.Error)
+	return b.String()
+}
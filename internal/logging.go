@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logger receives the structured log of a run. It discards everything unless
+// --log-format or --log-file turns logging on (see setupLogger).
+var logger = slog.New(slog.DiscardHandler)
+
+// setupLogger configures the run's structured log and returns a function that
+// closes the log file. The level follows the output flags: --verbose logs every
+// request (debug), --quiet only warnings and errors.
+func setupLogger(config RunConfig) (func(), error) {
+	if config.LogFormat == "" && config.LogFile == "" {
+		return func() {}, nil
+	}
+
+	level := slog.LevelInfo
+	if config.Verbose > 0 {
+		level = slog.LevelDebug
+	} else if config.Quiet {
+		level = slog.LevelWarn
+	}
+
+	// Without a log file the log goes to stderr, printed above the dashboard
+	var w io.Writer = screen.writer(os.Stderr)
+	closeLog := func() {}
+	if config.LogFile != "" {
+		file, err := os.OpenFile(config.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening log file: %v", err)
+		}
+		w = file
+		closeLog = func() { file.Close() }
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch config.LogFormat {
+	case "", LogFormatText:
+		logger = slog.New(slog.NewTextHandler(w, options))
+	case LogFormatJSON:
+		logger = slog.New(slog.NewJSONHandler(w, options))
+	default:
+		closeLog()
+		return nil, fmt.Errorf("unknown --log-format %q (use text or json)", config.LogFormat)
+	}
+	return closeLog, nil
+}
+
+// printError prints an error for the user and logs it
+func printError(message string) {
+	screen.Printf("%s\n", colorize(colorRed, message))
+	logger.Error(strings.TrimPrefix(message, "Error: "))
+}
+
+// printWarning prints a warning for the user and logs it
+func printWarning(message string) {
+	screen.Printf("%s\n", colorize(colorYellow, message))
+	logger.Warn(strings.TrimPrefix(message, "Warning: "))
+}
+
+// logResult logs a finished request; result must already be redacted.
+// Successful requests are debug entries unless they carry a recorded request,
+// so a run log written with --record-requests can replay every row.
+func logResult(item PostmanItem, result RequestResult) {
+	attrs := []any{"item", item.Name, "row", result.RowIndex, "method", result.Method, "url", result.URL,
+		"status", result.StatusCode, "duration_ms", result.ResponseTime.Milliseconds(), "retries", result.Retries}
+	if result.Request != nil {
+		attrs = append(attrs, "request", result.Request)
+	}
+	if result.Success {
+		if result.Request != nil {
+			logger.Info("request sent", attrs...)
+			return
+		}
+		logger.Debug("request sent", attrs...)
+		return
+	}
+	logger.Warn("request failed", append(attrs, "error", result.Error)...)
+}
@@ -0,0 +1,446 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// timelineBuckets is the number of time windows latency percentiles are reported over
+	timelineBuckets = 20
+	// topErrorCount is the number of distinct error messages kept per item
+	topErrorCount = 10
+	// failedSampleCount is the number of failed rows kept per item in the metrics file
+	failedSampleCount = 20
+)
+
+// LatencySample is the response time of a single request and when it completed
+type LatencySample struct {
+	Offset   time.Duration // Completion time relative to the item's start
+	Duration time.Duration
+}
+
+// MetricsReport is the JSON document written to the metrics file.
+// It is also the input for the HTML report, so field names are part of the file format.
+type MetricsReport struct {
+	RunID           string              `json:"run_id"`
+	CollectionName  string              `json:"collection_name"`
+	CSVFile         string              `json:"csv_file"`
+	StartTime       string              `json:"start_time"`
+	EndTime         string              `json:"end_time"`
+	DurationSeconds float64             `json:"duration_seconds"`
+	TotalRecords    int                 `json:"total_records"`
+	Summary         MetricsSummary      `json:"summary"`
+	Connections     ConnectionReport    `json:"connections"`
+	Protocols       map[string]int64    `json:"protocols"`
+	Items           []ItemMetricsReport `json:"items"`
+	Selection       *SelectionReport    `json:"selection,omitempty"` // Present when items were selected
+	Dedupe          *DedupeReport       `json:"dedupe,omitempty"`    // Present when duplicate rows were removed
+	Rows            *RowFilterReport    `json:"rows,omitempty"`      // Present when CSV rows were filtered
+	Canary          *CanaryReport       `json:"canary,omitempty"`    // Present with a canary phase; items and summary include the canary rows
+}
+
+// MetricsSummary aggregates counts over all items
+type MetricsSummary struct {
+	TotalRequests  int64   `json:"total_requests"`
+	Successful     int64   `json:"successful"`
+	Failed         int64   `json:"failed"`
+	SuccessRatePct float64 `json:"success_rate_pct"`
+
+	// Requests that failed before being sent because credentials could not be
+	// obtained, and the OAuth2 token requests behind them
+	AuthFailures       int64 `json:"auth_failures"`
+	TokenFetches       int64 `json:"token_fetches"`
+	TokenFetchFailures int64 `json:"token_fetch_failures"`
+
+	// Extra attempts made for transport errors and retryable status codes
+	Retries int64 `json:"retries"`
+
+	// Rows not sent because a canary stopped the run
+	Skipped int64 `json:"skipped"`
+}
+
+// ItemMetricsReport holds the metrics of a single collection item
+type ItemMetricsReport struct {
+	Name            string           `json:"name"`
+	TotalRequests   int64            `json:"total_requests"`
+	Successful      int64            `json:"successful"`
+	Failed          int64            `json:"failed"`
+	SuccessRatePct  float64          `json:"success_rate_pct"`
+	AuthFailures    int64            `json:"auth_failures"`
+	Retries         int64            `json:"retries"`
+	Skipped         int64            `json:"skipped"`
+	Timing          TimingReport     `json:"timing"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	StatusCodes     map[string]int64 `json:"status_codes"`
+	Connections     ConnectionReport `json:"connections"`
+	Protocols       map[string]int64 `json:"protocols"`
+	Timeline        []TimelineBucket `json:"timeline"`
+	TopErrors       []ErrorCount     `json:"top_errors"`
+	FailedSamples   []FailedSample   `json:"failed_samples"`
+}
+
+// TimingReport summarizes response times in milliseconds
+type TimingReport struct {
+	AvgMs int64 `json:"avg_ms"`
+	MinMs int64 `json:"min_ms"`
+	MaxMs int64 `json:"max_ms"`
+	P50Ms int64 `json:"p50_ms"`
+	P90Ms int64 `json:"p90_ms"`
+	P95Ms int64 `json:"p95_ms"`
+	P99Ms int64 `json:"p99_ms"`
+
+	TLSHandshakes     int64 `json:"tls_handshakes"`
+	TLSHandshakeAvgMs int64 `json:"tls_handshake_avg_ms"`
+}
+
+// ConnectionReport shows how well the connection pool was reused
+type ConnectionReport struct {
+	New          int64   `json:"new"`
+	Reused       int64   `json:"reused"`
+	ReuseRatePct float64 `json:"reuse_rate_pct"`
+}
+
+// TimelineBucket holds latency percentiles for requests completed in one time window
+type TimelineBucket struct {
+	OffsetSeconds float64 `json:"offset_seconds"`
+	Requests      int     `json:"requests"`
+	P50Ms         int64   `json:"p50_ms"`
+	P90Ms         int64   `json:"p90_ms"`
+	P99Ms         int64   `json:"p99_ms"`
+}
+
+// ErrorCount is a distinct error message and how often it occurred
+type ErrorCount struct {
+	Message string `json:"message"`
+	Count   int64  `json:"count"`
+}
+
+// FailedSample is one failed row kept for the report
+type FailedSample struct {
+	Row        int               `json:"row"`
+	StatusCode int               `json:"status_code"`
+	Error      string            `json:"error"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method"`
+	Data       map[string]string `json:"data"`
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// recordResult adds a request result to the item metrics
+func (m *RequestMetrics) recordResult(result RequestResult) {
+	if result.Success {
+		m.SuccessCount++
+	} else {
+		m.FailureCount++
+		m.FailedRequests = append(m.FailedRequests, result)
+	}
+
+	// Update timing metrics
+	if result.ResponseTime < m.MinTime {
+		m.MinTime = result.ResponseTime
+	}
+	if result.ResponseTime > m.MaxTime {
+		m.MaxTime = result.ResponseTime
+	}
+	m.TotalTime += result.ResponseTime
+
+	if m.StatusCodes == nil {
+		m.StatusCodes = make(map[int]int64)
+	}
+	m.StatusCodes[result.StatusCode]++
+	if result.StatusCode != 0 {
+		if result.ConnReused {
+			m.ReusedConns++
+		} else {
+			m.NewConns++
+		}
+		if m.Protocols == nil {
+			m.Protocols = make(map[string]int64)
+		}
+		m.Protocols[result.Protocol]++
+	}
+	if result.AuthError {
+		m.AuthFailures++
+	}
+	m.Retries += int64(result.Retries)
+	if result.TLSHandshake > 0 {
+		m.TLSHandshakes++
+		m.TLSTime += result.TLSHandshake
+	}
+	m.Latencies = append(m.Latencies, LatencySample{
+		Offset:   time.Since(m.StartTime),
+		Duration: result.ResponseTime,
+	})
+}
+
+// merge adds the metrics of a later run of the same item, such as the rows
+// sent after its canary
+func (m *RequestMetrics) merge(other RequestMetrics) {
+	m.TotalRequests += other.TotalRequests
+	m.SuccessCount += other.SuccessCount
+	m.FailureCount += other.FailureCount
+	m.FailedRequests = append(m.FailedRequests, other.FailedRequests...)
+	m.MinTime = min(m.MinTime, other.MinTime)
+	m.MaxTime = max(m.MaxTime, other.MaxTime)
+	m.TotalTime += other.TotalTime
+	m.EndTime = other.EndTime
+
+	if m.StatusCodes == nil {
+		m.StatusCodes = make(map[int]int64)
+	}
+	for code, count := range other.StatusCodes {
+		m.StatusCodes[code] += count
+	}
+	if m.Protocols == nil {
+		m.Protocols = make(map[string]int64)
+	}
+	for protocol, count := range other.Protocols {
+		m.Protocols[protocol] += count
+	}
+	m.NewConns += other.NewConns
+	m.ReusedConns += other.ReusedConns
+	m.TLSHandshakes += other.TLSHandshakes
+	m.TLSTime += other.TLSTime
+	m.AuthFailures += other.AuthFailures
+	m.Retries += other.Retries
+	m.Skipped += other.Skipped
+
+	// Latency offsets are relative to the item's start
+	shift := other.StartTime.Sub(m.StartTime)
+	for _, sample := range other.Latencies {
+		sample.Offset += shift
+		m.Latencies = append(m.Latencies, sample)
+	}
+}
+
+// buildMetricsReport converts run metrics into the metrics file structure
+func buildMetricsReport(runMetrics *RunMetrics) MetricsReport {
+	report := MetricsReport{
+		RunID:           runMetrics.RunID,
+		CollectionName:  runMetrics.CollectionName,
+		CSVFile:         runMetrics.CSVFile,
+		StartTime:       runMetrics.StartTime.Format(time.RFC3339),
+		EndTime:         runMetrics.EndTime.Format(time.RFC3339),
+		DurationSeconds: runMetrics.EndTime.Sub(runMetrics.StartTime).Seconds(),
+		TotalRecords:    runMetrics.TotalRecords,
+		Protocols:       map[string]int64{},
+		Items:           []ItemMetricsReport{},
+		Selection:       runMetrics.Selection,
+		Dedupe:          runMetrics.Dedupe,
+		Rows:            runMetrics.Rows,
+		Canary:          runMetrics.Canary,
+	}
+
+	for _, item := range runMetrics.ItemMetrics {
+		report.Summary.Successful += item.SuccessCount
+		report.Summary.Failed += item.FailureCount
+		report.Summary.TotalRequests += item.TotalRequests
+		report.Summary.AuthFailures += item.AuthFailures
+		report.Summary.Retries += item.Retries
+		report.Summary.Skipped += item.Skipped
+		report.Connections.New += item.NewConns
+		report.Connections.Reused += item.ReusedConns
+		for protocol, count := range item.Protocols {
+			report.Protocols[protocol] += count
+		}
+		report.Items = append(report.Items, buildItemReport(item))
+	}
+	report.Summary.SuccessRatePct = percentOf(report.Summary.Successful, report.Summary.TotalRequests)
+	report.Summary.TokenFetches = runMetrics.TokenFetches
+	report.Summary.TokenFetchFailures = runMetrics.TokenFetchFailures
+	report.Connections.ReuseRatePct = percentOf(report.Connections.Reused, report.Connections.New+report.Connections.Reused)
+
+	return report
+}
+
+// buildItemReport computes the per-item section of the metrics file
+func buildItemReport(item RequestMetrics) ItemMetricsReport {
+	completed := item.SuccessCount + item.FailureCount
+	avgTime := time.Duration(0)
+	if completed > 0 {
+		avgTime = item.TotalTime / time.Duration(completed)
+	}
+
+	durations := make([]time.Duration, len(item.Latencies))
+	for i, sample := range item.Latencies {
+		durations[i] = sample.Duration
+	}
+	sortDurations(durations)
+
+	report := ItemMetricsReport{
+		Name:           item.Name,
+		TotalRequests:  item.TotalRequests,
+		Successful:     item.SuccessCount,
+		Failed:         item.FailureCount,
+		SuccessRatePct: percentOf(item.SuccessCount, item.TotalRequests),
+		AuthFailures:   item.AuthFailures,
+		Retries:        item.Retries,
+		Skipped:        item.Skipped,
+		Timing: TimingReport{
+			AvgMs: avgTime.Milliseconds(),
+			MinMs: item.MinTime.Milliseconds(),
+			MaxMs: item.MaxTime.Milliseconds(),
+			P50Ms: percentile(durations, 50).Milliseconds(),
+			P90Ms: percentile(durations, 90).Milliseconds(),
+			P95Ms: percentile(durations, 95).Milliseconds(),
+			P99Ms: percentile(durations, 99).Milliseconds(),
+
+			TLSHandshakes: item.TLSHandshakes,
+		},
+		DurationSeconds: item.EndTime.Sub(item.StartTime).Seconds(),
+		StatusCodes:     map[string]int64{},
+		Connections: ConnectionReport{
+			New:          item.NewConns,
+			Reused:       item.ReusedConns,
+			ReuseRatePct: percentOf(item.ReusedConns, item.NewConns+item.ReusedConns),
+		},
+		Protocols:     map[string]int64{},
+		Timeline:      buildTimeline(item.Latencies, item.EndTime.Sub(item.StartTime)),
+		TopErrors:     topErrors(item.FailedRequests, topErrorCount),
+		FailedSamples: []FailedSample{},
+	}
+
+	if item.TLSHandshakes > 0 {
+		report.Timing.TLSHandshakeAvgMs = (item.TLSTime / time.Duration(item.TLSHandshakes)).Milliseconds()
+	}
+
+	for code, count := range item.StatusCodes {
+		report.StatusCodes[strconv.Itoa(code)] = count
+	}
+	for protocol, count := range item.Protocols {
+		report.Protocols[protocol] = count
+	}
+
+	for i, fr := range item.FailedRequests {
+		if i >= failedSampleCount {
+			break
+		}
+		report.FailedSamples = append(report.FailedSamples, FailedSample{
+			Row:        fr.RowIndex,
+			StatusCode: fr.StatusCode,
+			Error:      cleanErrorMessage(fr.Error),
+			URL:        fr.URL,
+			Method:     fr.Method,
+			Data:       fr.CSVData,
+
+			IdempotencyKey: fr.IdempotencyKey,
+		})
+	}
+
+	return report
+}
+
+// buildTimeline splits an item's run into equal windows and computes latency percentiles for each
+func buildTimeline(samples []LatencySample, total time.Duration) []TimelineBucket {
+	timeline := []TimelineBucket{}
+	if len(samples) == 0 || total <= 0 {
+		return timeline
+	}
+
+	width := total / timelineBuckets
+	if width <= 0 {
+		width = total
+	}
+
+	buckets := make([][]time.Duration, timelineBuckets)
+	for _, sample := range samples {
+		idx := int(sample.Offset / width)
+		if idx >= timelineBuckets {
+			idx = timelineBuckets - 1
+		}
+		buckets[idx] = append(buckets[idx], sample.Duration)
+	}
+
+	for i, durations := range buckets {
+		if len(durations) == 0 {
+			continue
+		}
+		sortDurations(durations)
+		timeline = append(timeline, TimelineBucket{
+			OffsetSeconds: (time.Duration(i+1) * width).Seconds(),
+			Requests:      len(durations),
+			P50Ms:         percentile(durations, 50).Milliseconds(),
+			P90Ms:         percentile(durations, 90).Milliseconds(),
+			P99Ms:         percentile(durations, 99).Milliseconds(),
+		})
+	}
+	return timeline
+}
+
+// errorURLPattern matches request URLs embedded in Go client error messages
+var errorURLPattern = regexp.MustCompile(`https?://[^\s"]+`)
+
+// topErrors groups failed requests by error message and returns the most frequent ones.
+// URLs are removed before grouping so the same failure on different rows counts once.
+func topErrors(failed []RequestResult, limit int) []ErrorCount {
+	counts := make(map[string]int64)
+	for _, fr := range failed {
+		counts[errorURLPattern.ReplaceAllString(cleanErrorMessage(fr.Error), "<url>")]++
+	}
+
+	errors := make([]ErrorCount, 0, len(counts))
+	for message, count := range counts {
+		errors = append(errors, ErrorCount{Message: message, Count: count})
+	}
+	sort.Slice(errors, func(i, j int) bool {
+		if errors[i].Count != errors[j].Count {
+			return errors[i].Count > errors[j].Count
+		}
+		return errors[i].Message < errors[j].Message
+	})
+
+	if len(errors) > limit {
+		errors = errors[:limit]
+	}
+	return errors
+}
+
+// sortDurations sorts durations in ascending order
+func sortDurations(durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+}
+
+// percentile returns the p-th percentile of sorted durations using the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// percentOf returns part as a percentage of total, or 0 when total is 0
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// LoadMetricsReport reads a metrics JSON file written by a previous run
+func LoadMetricsReport(path string) (*MetricsReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading metrics file: %v", err)
+	}
+
+	var report MetricsReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error parsing metrics JSON: %v", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenExpirySkew is how long before expiry a cached token is refreshed, so
+// requests in flight never carry a token that expires on the way. Tokens
+// living less than five times this long are refreshed after 80% of their lifetime.
+const tokenExpirySkew = 30 * time.Second
+
+// oauth2Params holds the rendered OAuth2 settings of one request.
+// Field names follow the keys of Postman's oauth2 auth export.
+type oauth2Params struct {
+	GrantType    string // client_credentials, password, refresh_token
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	Audience     string
+	Username     string
+	Password     string
+	RefreshToken string
+	ClientAuth   string // "header" (HTTP basic, default) or "body"
+	HeaderPrefix string
+	AccessToken  string // Static token from the export, used when there is no token URL
+}
+
+// extractOAuth2 reads oauth2 settings in object or array format and renders template variables
+func extractOAuth2(raw json.RawMessage, csvData map[string]string) oauth2Params {
+	params := extractAuthParams(raw)
+	render := func(key string) string {
+		return replaceTemplateVariables(params[key], csvData)
+	}
+
+	grantType := render("grant_type")
+	switch grantType {
+	case "", "client_credentials":
+		grantType = "client_credentials"
+	case "password_credentials":
+		grantType = "password"
+	}
+
+	return oauth2Params{
+		GrantType:    grantType,
+		TokenURL:     render("accessTokenUrl"),
+		ClientID:     render("clientId"),
+		ClientSecret: render("clientSecret"),
+		Scope:        render("scope"),
+		Audience:     render("audience"),
+		Username:     render("username"),
+		Password:     render("password"),
+		RefreshToken: render("refreshToken"),
+		ClientAuth:   render("client_authentication"),
+		HeaderPrefix: render("headerPrefix"),
+		AccessToken:  render("accessToken"),
+	}
+}
+
+// cacheKey identifies a set of credentials; rows sharing credentials share a token
+func (p oauth2Params) cacheKey() string {
+	return strings.Join([]string{p.GrantType, p.TokenURL, p.ClientID, p.ClientSecret,
+		p.Scope, p.Audience, p.Username, p.Password, p.RefreshToken}, "\x00")
+}
+
+// tokenEntry is a cached access token. Its mutex is held while fetching so
+// concurrent workers needing the same token wait for a single request.
+type tokenEntry struct {
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	refreshAt    time.Time // Zero when the server did not send expires_in
+}
+
+// valid reports whether the token can still be used
+func (e *tokenEntry) valid() bool {
+	if e.accessToken == "" {
+		return false
+	}
+	return e.refreshAt.IsZero() || time.Now().Before(e.refreshAt)
+}
+
+// tokenCache fetches OAuth2 access tokens and shares them across all workers
+type tokenCache struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[string]*tokenEntry
+
+	fetches  int64
+	failures int64
+}
+
+// newTokenCache creates a token cache that fetches tokens with the run's HTTP client
+func newTokenCache(client *http.Client) *tokenCache {
+	return &tokenCache{
+		client:  client,
+		entries: make(map[string]*tokenEntry),
+	}
+}
+
+// tokenError is a failed token fetch while sending a request. It is reported as
+// an auth failure rather than a network error.
+type tokenError struct {
+	err error
+}
+
+func (e *tokenError) Error() string { return e.err.Error() }
+
+func (e *tokenError) Unwrap() error { return e.err }
+
+// Token returns a valid access token, fetching or refreshing it when needed
+func (c *tokenCache) Token(params oauth2Params) (string, error) {
+	if params.TokenURL == "" {
+		if params.AccessToken != "" {
+			return params.AccessToken, nil
+		}
+		return "", fmt.Errorf("oauth2 auth has neither accessTokenUrl nor accessToken")
+	}
+
+	entry := c.entry(params)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.valid() {
+		return entry.accessToken, nil
+	}
+
+	// Prefer the refresh token from the previous response, then fall back to a new grant
+	if entry.refreshToken != "" {
+		if err := c.fetch(params, entry, "refresh_token", entry.refreshToken); err == nil {
+			return entry.accessToken, nil
+		}
+	}
+	if err := c.fetch(params, entry, params.GrantType, params.RefreshToken); err != nil {
+		return "", err
+	}
+	return entry.accessToken, nil
+}
+
+// Invalidate drops a token the server rejected so the next Token call fetches a new one.
+// A token that was already replaced by another worker is left alone.
+func (c *tokenCache) Invalidate(params oauth2Params, token string) {
+	entry := c.entry(params)
+	entry.mu.Lock()
+	if entry.accessToken == token {
+		entry.accessToken = ""
+	}
+	entry.mu.Unlock()
+}
+
+// Stats returns the number of token requests and how many of them failed
+func (c *tokenCache) Stats() (fetches, failures int64) {
+	return atomic.LoadInt64(&c.fetches), atomic.LoadInt64(&c.failures)
+}
+
+func (c *tokenCache) entry(params oauth2Params) *tokenEntry {
+	key := params.cacheKey()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &tokenEntry{}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// fetch requests a token from the token endpoint and stores it in entry
+func (c *tokenCache) fetch(params oauth2Params, entry *tokenEntry, grantType, refreshToken string) error {
+	atomic.AddInt64(&c.fetches, 1)
+	if err := c.requestToken(params, entry, grantType, refreshToken); err != nil {
+		atomic.AddInt64(&c.failures, 1)
+		return err
+	}
+	return nil
+}
+
+func (c *tokenCache) requestToken(params oauth2Params, entry *tokenEntry, grantType, refreshToken string) error {
+	form := url.Values{}
+	form.Set("grant_type", grantType)
+	switch grantType {
+	case "refresh_token":
+		if refreshToken == "" {
+			return fmt.Errorf("refresh_token grant requires a refreshToken")
+		}
+		form.Set("refresh_token", refreshToken)
+	case "password":
+		form.Set("username", params.Username)
+		form.Set("password", params.Password)
+	}
+	if params.Scope != "" {
+		form.Set("scope", params.Scope)
+	}
+	if params.Audience != "" {
+		form.Set("audience", params.Audience)
+	}
+	if params.ClientAuth == "body" {
+		form.Set("client_id", params.ClientID)
+		form.Set("client_secret", params.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, params.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("invalid token URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if params.ClientAuth != "body" && params.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(params.ClientID), url.QueryEscape(params.ClientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading token response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := string(body)
+		if len(message) > 100 {
+			message = message[:100] + "..."
+		}
+		return fmt.Errorf("token endpoint returned HTTP %d: %s", resp.StatusCode, message)
+	}
+
+	var token struct {
+		AccessToken  string      `json:"access_token"`
+		RefreshToken string      `json:"refresh_token"`
+		ExpiresIn    json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("error parsing token response: %v", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("token response has no access_token")
+	}
+
+	entry.accessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		entry.refreshToken = token.RefreshToken
+	}
+	entry.refreshAt = time.Time{}
+	if seconds, err := token.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		lifetime := time.Duration(seconds) * time.Second
+		skew := tokenExpirySkew
+		if lifetime/5 < skew {
+			skew = lifetime / 5
+		}
+		entry.refreshAt = time.Now().Add(lifetime - skew)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Parallel scopes: what runs concurrently when ParallelItems is above 1
+const (
+	ParallelScopeItem   = "item"   // Any selected requests
+	ParallelScopeFolder = "folder" // Top-level folders; the requests inside a folder still run in order
+)
+
+// Worker budgets of concurrently running items
+const (
+	WorkerBudgetShared  = "shared"   // At most --threads requests in flight across all items
+	WorkerBudgetPerItem = "per-item" // Every running item has --threads workers of its own
+)
+
+// validateParallel checks the parallel item settings of a run
+func validateParallel(config RunConfig) error {
+	if config.ParallelItems < 0 {
+		return fmt.Errorf("--parallel-items must not be negative")
+	}
+	switch config.ParallelScope {
+	case "", ParallelScopeItem, ParallelScopeFolder:
+	default:
+		return fmt.Errorf("unknown --parallel-scope %q (use item or folder)", config.ParallelScope)
+	}
+	switch config.WorkerBudget {
+	case "", WorkerBudgetShared, WorkerBudgetPerItem:
+	default:
+		return fmt.Errorf("unknown --worker-budget %q (use shared or per-item)", config.WorkerBudget)
+	}
+	return nil
+}
+
+// newWorkerBudget returns the slots shared by all workers of concurrently
+// running items, or nil when items run one at a time or have their own workers
+func newWorkerBudget(config RunConfig) chan struct{} {
+	if config.ParallelItems <= 1 || config.WorkerBudget == WorkerBudgetPerItem {
+		return nil
+	}
+	return make(chan struct{}, config.Threads)
+}
+
+// parallel reports whether items of the run execute concurrently
+func (r *batchRunner) parallel() bool {
+	return r.config.ParallelItems > 1
+}
+
+// processItems runs the items of a collection, one after another in collection
+// order or up to ParallelItems at a time. Item metrics are recorded in
+// collection order either way. records returns the rows of an item, or of a
+// top-level folder, by its path.
+func (r *batchRunner) processItems(items []PostmanItem, records func(itemPath string) []csvRecord, runMetrics *RunMetrics) {
+	if !r.parallel() {
+		for _, item := range items {
+			r.processItem(item, item.Name, records(item.Name), runMetrics, 0)
+		}
+		return
+	}
+
+	type unit struct {
+		item     PostmanItem
+		itemPath string
+	}
+	var units []unit
+	if r.config.ParallelScope == ParallelScopeFolder {
+		for _, item := range items {
+			units = append(units, unit{item, item.Name})
+		}
+	} else {
+		walkRequests(items, "", func(item PostmanItem, itemPath string) {
+			units = append(units, unit{item, itemPath})
+		})
+	}
+
+	unitMetrics := make([]*RunMetrics, len(units))
+	slots := make(chan struct{}, r.config.ParallelItems)
+	var wg sync.WaitGroup
+	for i, u := range units {
+		unitMetrics[i] = &RunMetrics{}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			r.processItem(u.item, u.itemPath, records(u.itemPath), unitMetrics[i], 0)
+		}()
+	}
+	wg.Wait()
+
+	for _, metrics := range unitMetrics {
+		runMetrics.ItemMetrics = append(runMetrics.ItemMetrics, metrics.ItemMetrics...)
+	}
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyFunc selects the proxy for a request, as used by http.Transport.Proxy
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// newProxyFunc returns the proxy selection for a run. Without --proxy the
+// standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables apply.
+// With --proxy every request goes through that proxy unless its host
+// matches an entry of --no-proxy.
+func newProxyFunc(config RunConfig) (proxyFunc, error) {
+	if config.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(config.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", config.Proxy)
+	}
+
+	bypass := parseNoProxy(config.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass.matches(req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// noProxyRules is a parsed --no-proxy list
+type noProxyRules struct {
+	all     bool
+	domains []string // Matches the host itself and all its subdomains
+	hosts   []string // host:port entries, matched exactly
+	nets    []*net.IPNet
+}
+
+// parseNoProxy parses a comma separated list of hosts, domains (example.com,
+// .example.com or *.example.com), host:port pairs, IP addresses and CIDRs.
+// A single "*" disables the proxy for every host.
+func parseNoProxy(entries []string) noProxyRules {
+	var rules noProxyRules
+	for _, entry := range entries {
+		for _, part := range strings.Split(entry, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			switch {
+			case part == "":
+			case part == "*":
+				rules.all = true
+			case strings.Contains(part, "/"):
+				if _, ipNet, err := net.ParseCIDR(part); err == nil {
+					rules.nets = append(rules.nets, ipNet)
+				}
+			case net.ParseIP(part) != nil:
+				ip := net.ParseIP(part)
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				rules.nets = append(rules.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			case strings.Contains(part, ":"):
+				rules.hosts = append(rules.hosts, part)
+			default:
+				rules.domains = append(rules.domains, strings.TrimPrefix(strings.TrimPrefix(part, "*"), "."))
+			}
+		}
+	}
+	return rules
+}
+
+// matches reports whether a request URL bypasses the proxy
+func (r noProxyRules) matches(u *url.URL) bool {
+	if r.all {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		for _, ipNet := range r.nets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	hostPort := net.JoinHostPort(host, port)
+	for _, entry := range r.hosts {
+		if entry == hostPort {
+			return true
+		}
+	}
+
+	for _, domain := range r.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeProxy returns the proxy a request would use with the password masked
+func describeProxy(proxy proxyFunc, req *http.Request) string {
+	proxyURL, err := proxy(req)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	if proxyURL == nil {
+		return "direct"
+	}
+	return proxyURL.Redacted()
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces every secret in output
+const redactedValue = "****"
+
+// minSecretLength keeps short values such as "1" or "eu" from being scrubbed everywhere
+const minSecretLength = 4
+
+// sensitiveName matches header and query parameter names whose values are always masked
+var sensitiveName = regexp.MustCompile(`(?i)(auth|token|secret|passw|pwd|api[-_]?key|access[-_]?key|signature|session|cookie|credential|^key$|^sig$)`)
+
+// credentialKeys are the Postman auth fields whose rendered values are secrets
+var credentialKeys = map[string]bool{
+	"token":        true,
+	"value":        true,
+	"password":     true,
+	"clientSecret": true,
+	"accessToken":  true,
+	"refreshToken": true,
+	"secretKey":    true,
+	"sessionToken": true,
+	"authKey":      true,
+}
+
+// redactor masks secrets before anything is printed, saved or exported.
+// Built-in rules cover sensitive header and query parameter names and the
+// credentials of the request's auth; users add regex patterns and CSV columns.
+type redactor struct {
+	patterns   []*regexp.Regexp
+	columns    map[string]bool
+	references map[string]string // Resolved {{secret:...}} value -> reference text
+}
+
+// newRedactor compiles --redact-pattern expressions and collects --redact-column names.
+// A pattern with a capture group masks only the first group, otherwise the whole match.
+func newRedactor(patterns, columns []string) (*redactor, error) {
+	r := &redactor{columns: make(map[string]bool)}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, entry := range columns {
+		for _, column := range strings.Split(entry, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				r.columns[column] = true
+			}
+		}
+	}
+	return r, nil
+}
+
+// rowSecrets returns the values to scrub from output for one row: the rendered
+// credentials of its auth and the values of redacted columns
+func (r *redactor) rowSecrets(auth *PostmanAuth, csvData map[string]string) []string {
+	var secrets []string
+	add := func(value string) {
+		if len(value) >= minSecretLength && !strings.Contains(value, "{{") {
+			secrets = append(secrets, value)
+		}
+	}
+
+	// Resolved secret references are scrubbed whatever their length
+	secrets = append(secrets, r.referenceSecrets()...)
+	for column := range r.columns {
+		add(csvData[column])
+	}
+	if auth != nil {
+		for key, value := range extractAuthParams(authRaw(auth)) {
+			if credentialKeys[key] {
+				add(replaceTemplateVariables(value, csvData))
+			}
+		}
+	}
+	// Longest first, so a secret containing another one is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// referenceSecrets returns the resolved values of {{secret:...}} references,
+// longest first, for output that does not belong to a row
+func (r *redactor) referenceSecrets() []string {
+	secrets := make([]string, 0, len(r.references))
+	for value := range r.references {
+		secrets = append(secrets, value)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// authRaw returns the parameters of the auth's own type
+func authRaw(auth *PostmanAuth) []byte {
+	switch auth.Type {
+	case "bearer":
+		return auth.BearerRaw
+	case "apikey":
+		return auth.APIKeyRaw
+	case "basic":
+		return auth.BasicRaw
+	case "oauth2":
+		return auth.OAuth2Raw
+	case "awsv4":
+		return auth.AWSv4Raw
+	case "digest":
+		return auth.DigestRaw
+	case "hawk":
+		return auth.HawkRaw
+	}
+	return nil
+}
+
+// String scrubs secret values and user patterns from free text
+func (r *redactor) String(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			s = strings.ReplaceAll(s, escaped, redactedValue)
+		}
+	}
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllString(s, redactedValue)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			loc := re.FindStringSubmatchIndex(match)
+			if loc[2] < 0 {
+				return match
+			}
+			return match[:loc[2]] + redactedValue + match[loc[3]:]
+		})
+	}
+	return s
+}
+
+// URL masks the userinfo password and sensitive query parameters, then scrubs the rest
+func (r *redactor) URL(rawURL string, secrets []string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			rawURL = strings.Replace(rawURL, parsed.User.String()+"@", url.PathEscape(parsed.User.Username())+":"+redactedValue+"@", 1)
+		}
+	}
+
+	if base, query, ok := strings.Cut(rawURL, "?"); ok {
+		fragment := ""
+		if i := strings.IndexByte(query, '#'); i >= 0 {
+			query, fragment = query[:i], query[i:]
+		}
+		pairs := strings.Split(query, "&")
+		for i, pair := range pairs {
+			key, _, hasValue := strings.Cut(pair, "=")
+			name, err := url.QueryUnescape(key)
+			if hasValue && err == nil && sensitiveName.MatchString(name) {
+				pairs[i] = key + "=" + redactedValue
+			}
+		}
+		rawURL = base + "?" + strings.Join(pairs, "&") + fragment
+	}
+
+	return r.String(rawURL, secrets)
+}
+
+// Header masks sensitive headers, keeping the scheme of Authorization style values
+func (r *redactor) Header(name, value string, secrets []string) string {
+	if sensitiveName.MatchString(name) {
+		if scheme, _, ok := strings.Cut(value, " "); ok && !strings.ContainsAny(scheme, "=;") {
+			return scheme + " " + redactedValue
+		}
+		return redactedValue
+	}
+	return r.String(value, secrets)
+}
+
+// Row returns a copy of a CSV row with redacted columns masked and resolved
+// secrets turned back into their {{secret:...}} references. Other columns are
+// kept so failed request CSVs can still be re-run.
+func (r *redactor) Row(csvData map[string]string) map[string]string {
+	if len(r.columns) == 0 && len(r.references) == 0 {
+		return csvData
+	}
+	row := make(map[string]string, len(csvData))
+	for column, value := range csvData {
+		if r.columns[column] && value != "" {
+			value = redactedValue
+		}
+		row[column] = r.reference(value)
+	}
+	return row
+}
+
+// reference turns resolved secrets in a value back into their {{secret:...}} references
+func (r *redactor) reference(value string) string {
+	for secret, ref := range r.references {
+		value = strings.ReplaceAll(value, secret, ref)
+	}
+	return value
+}
+
+// Request masks a request recorded for replay. Resolved secrets become their
+// {{secret:...}} references again, which replay resolves; other secrets are
+// masked and have to be given to replay. The traceparent header is left out
+// because every send starts a new trace.
+func (r *redactor) Request(item string, detail *requestDetail, method string, secrets []string) *RecordedRequest {
+	recorded := &RecordedRequest{
+		Item:    item,
+		Method:  method,
+		URL:     r.URL(r.reference(detail.requestURL), secrets),
+		Headers: http.Header{},
+		Body:    r.String(r.reference(detail.requestBody), secrets),
+	}
+	for name, values := range detail.requestHeader {
+		if strings.EqualFold(name, "traceparent") {
+			continue
+		}
+		for _, value := range values {
+			if withRefs := r.reference(value); withRefs != value {
+				value = r.String(withRefs, secrets)
+			} else {
+				value = r.Header(name, value, secrets)
+			}
+			recorded.Headers.Add(name, value)
+		}
+	}
+	return recorded
+}
+
+// Result redacts everything of a result that ends up in output files, traces and the console
+func (r *redactor) Result(result RequestResult, secrets []string) RequestResult {
+	result.URL = r.URL(result.URL, secrets)
+	result.Error = r.String(result.Error, secrets)
+	result.Message = r.String(result.Message, secrets)
+	result.CSVData = r.Row(result.CSVData)
+	result.RecordInfo = getRecordInfo(result.CSVData)
+	return result
+}
@@ -0,0 +1,402 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Columns of a recorded request in failed request CSVs (--record-requests)
+const (
+	requestItemColumn    = "_request_item"
+	requestMethodColumn  = "_request_method"
+	requestURLColumn     = "_request_url"
+	requestHeadersColumn = "_request_headers" // JSON object of header value lists
+	requestBodyColumn    = "_request_body"
+)
+
+var requestColumns = []string{requestItemColumn, requestMethodColumn, requestURLColumn, requestHeadersColumn, requestBodyColumn}
+
+// maxLogLine bounds a JSON run log entry read by replay; entries hold request bodies
+const maxLogLine = 64 << 20
+
+// RecordedRequest is a request as it was sent, with secrets masked, saved in
+// failed request CSVs and the run log so it can be replayed
+type RecordedRequest struct {
+	Item    string      `json:"item"`
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// columns returns the failed request CSV values of a recorded request, in requestColumns order
+func (request *RecordedRequest) columns() []string {
+	headers, _ := json.Marshal(request.Headers)
+	return []string{request.Item, request.Method, request.URL, string(headers), request.Body}
+}
+
+// recordedColumnsOf returns the recorded request columns of a failed request:
+// its own recording, or the columns of the replayed row it came from
+func recordedColumnsOf(result RequestResult) []string {
+	if result.Request != nil {
+		return result.Request.columns()
+	}
+	if result.CSVData[requestMethodColumn] == "" {
+		return nil
+	}
+	values := make([]string, len(requestColumns))
+	for i, column := range requestColumns {
+		values[i] = result.CSVData[column]
+	}
+	return values
+}
+
+// recordedRequestOf reads the recorded request of a replayed row
+func recordedRequestOf(data map[string]string) (*RecordedRequest, error) {
+	request := &RecordedRequest{
+		Item:   data[requestItemColumn],
+		Method: data[requestMethodColumn],
+		URL:    data[requestURLColumn],
+		Body:   data[requestBodyColumn],
+	}
+	if request.Method == "" || request.URL == "" {
+		return nil, fmt.Errorf("no recorded request")
+	}
+	if headers := data[requestHeadersColumn]; headers != "" {
+		if err := json.Unmarshal([]byte(headers), &request.Headers); err != nil {
+			return nil, fmt.Errorf("invalid recorded headers: %v", err)
+		}
+	}
+	return request, nil
+}
+
+// loadRecordedRequests reads the rows to replay from a failed request CSV saved
+// with --record-requests or from a JSON run log. Log entries become rows of the
+// recorded request columns; failedOnly keeps the "request failed" entries.
+func loadRecordedRequests(path string, failedOnly bool) ([]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	start, _ := reader.Peek(512)
+	if !bytes.HasPrefix(bytes.TrimSpace(start), []byte("{")) {
+		rows, err := ReadCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			if _, ok := rows[0][requestMethodColumn]; !ok {
+				return nil, fmt.Errorf("%s has no recorded requests (save them with run --record-requests)", path)
+			}
+		}
+		return rows, nil
+	}
+
+	var rows []map[string]string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxLogLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry struct {
+			Msg     string           `json:"msg"`
+			Request *RecordedRequest `json:"request"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d is not a JSON log entry (replay reads logs written with --log-format json): %v", line, err)
+		}
+		if entry.Request == nil || (failedOnly && entry.Msg != "request failed") {
+			continue
+		}
+		values := entry.Request.columns()
+		row := make(map[string]string, len(values))
+		for i, column := range requestColumns {
+			row[column] = values[i]
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading log: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no recorded requests (log them with run --record-requests --log-format json)", path)
+	}
+	return rows, nil
+}
+
+// parseReplayHeaders parses "Name: value" header overrides, resolving secret references
+func parseReplayHeaders(specs []string, secrets *secretResolver) (http.Header, error) {
+	headers := http.Header{}
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --header %q (use \"Name: value\")", spec)
+		}
+		value, err := secrets.ResolveString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		headers.Add(strings.TrimSpace(name), value)
+	}
+	return headers, nil
+}
+
+// Replay re-sends the recorded requests of a failed request CSV or JSON run log
+// exactly as they were sent, through the same workers, retries, rate limit and
+// outputs as RunBatch. Requests are grouped into items by their recorded item.
+func Replay(path string, config RunConfig) {
+	startTime := time.Now()
+
+	closeLog, err := setupLogger(config)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	defer closeLog()
+
+	if config.Threads <= 0 {
+		printError("Error: Number of threads must be greater than 0")
+		return
+	}
+	if err := validateParallel(config); err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	// Failures of a replay can be replayed again
+	config.RecordRequests = true
+
+	secrets := newSecretResolver(config.VaultFile)
+	if config.BearerToken, err = secrets.ResolveString(config.BearerToken); err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	headers, err := parseReplayHeaders(config.ReplayHeaders, secrets)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	rows, err := loadRecordedRequests(path, config.ReplayFailedOnly)
+	if err != nil {
+		printError(fmt.Sprintf("Error reading recorded requests: %v", err))
+		return
+	}
+	if len(rows) == 0 {
+		printWarning("Warning: No recorded requests found in " + path)
+		return
+	}
+
+	if config.OutputDir != "" && !config.DryRun {
+		if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+			printError(fmt.Sprintf("Error creating output directory: %v", err))
+			return
+		}
+	}
+
+	// Recordings keep {{secret:...}} references in place of the values they resolved to
+	var items []PostmanItem
+	itemRecords := make(map[string][]csvRecord)
+	signed := 0
+	for i, row := range rows {
+		for _, column := range requestColumns {
+			resolve := secrets.ResolveString
+			if column == requestHeadersColumn {
+				resolve = func(text string) (string, error) {
+					resolved, err := secrets.ResolveJSON([]byte(text))
+					return string(resolved), err
+				}
+			}
+			if row[column], err = resolve(row[column]); err != nil {
+				printError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+		}
+		request, err := recordedRequestOf(row)
+		if err != nil {
+			printError(fmt.Sprintf("Error: row %d: %v", i+1, err))
+			return
+		}
+		if signedAuth(request.Headers) != "" {
+			signed++
+		}
+		name := request.Item
+		if name == "" {
+			name = "Replay"
+		}
+		if _, ok := itemRecords[name]; !ok {
+			items = append(items, PostmanItem{Name: name, Request: PostmanRequest{Method: request.Method, URL: PostmanURL{Raw: request.URL}}})
+		}
+		itemRecords[name] = append(itemRecords[name], csvRecord{Index: i + 1, Data: row})
+	}
+	if signed > 0 && headers.Get("Authorization") == "" && config.BearerToken == "" {
+		printWarning(fmt.Sprintf("Warning: %d recorded requests were signed (AWS Signature, Hawk or Digest auth) and cannot be replayed; they fail without being sent", signed))
+	}
+
+	runID := config.RunID
+	if runID == "" {
+		runID = newRunID()
+	}
+	logger = logger.With("run_id", runID)
+	logger.Info("replay started", "file", path, "requests", len(rows), "threads", config.Threads, "dry_run", config.DryRun)
+
+	runMetrics := &RunMetrics{
+		RunID:          runID,
+		CollectionName: "Replay of " + path,
+		CSVFile:        path,
+		StartTime:      startTime,
+		TotalRecords:   len(rows),
+		ItemMetrics:    []RequestMetrics{},
+	}
+
+	redactor, err := newRedactor(config.RedactPatterns, config.RedactColumns)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	redactor.references = secrets.References()
+
+	proxy, err := newProxyFunc(config)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	client, err := newHTTPClient(config, proxy)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if config.InsecureSkipVerify {
+		printInsecureWarning()
+	}
+
+	var tracer *Tracer
+	if !config.DryRun {
+		tracer, err = NewTracer(config.TraceServiceName, config.TraceEndpoint, config.TraceFile)
+		if err != nil {
+			printError(fmt.Sprintf("Error initializing tracing: %v", err))
+			return
+		}
+	}
+
+	runner := &batchRunner{
+		config:   config,
+		tracer:   tracer,
+		client:   client,
+		proxy:    proxy,
+		tokens:   newTokenCache(client),
+		digests:  newDigestCache(),
+		redactor: redactor,
+		limiter:  newRateLimiter(config.RateLimit, config.RateLimitBurst),
+		replay:   &replayOptions{headers: headers},
+		budget:   newWorkerBudget(config),
+	}
+
+	if !config.Quiet {
+		screen.Printf("%s\n", colorize(colorCyan+colorBold, "🔁 Replay: "+path))
+		screen.Printf("📊 Recorded requests: %s in %s\n\n",
+			colorize(colorYellow, fmt.Sprintf("%d", len(rows))), colorize(colorYellow, fmt.Sprintf("%d items", len(items))))
+		if runner.parallel() {
+			budget := fmt.Sprintf("%d requests in flight across all items", config.Threads)
+			if runner.budget == nil {
+				budget = fmt.Sprintf("%d workers per item", config.Threads)
+			}
+			screen.Printf("⚡ Replaying up to %d items at a time (%s)\n\n", config.ParallelItems, budget)
+		}
+	}
+
+	runner.processItems(items, func(name string) []csvRecord { return itemRecords[name] }, runMetrics)
+	runner.finishRun(runMetrics)
+}
+
+// signedAuthSchemes maps Authorization schemes computed from the request and the
+// time it was sent to the auth type names used in messages
+var signedAuthSchemes = map[string]string{
+	"AWS4-HMAC-SHA256": "AWS Signature",
+	"Hawk":             "Hawk",
+	"Digest":           "Digest",
+}
+
+// signedAuth returns the signed auth type of a request's Authorization header, or ""
+func signedAuth(header http.Header) string {
+	scheme, _, _ := strings.Cut(header.Get("Authorization"), " ")
+	return signedAuthSchemes[scheme]
+}
+
+// replayOptions changes how a batchRunner sends requests when replaying
+type replayOptions struct {
+	headers http.Header // Replace recorded headers of the same name
+}
+
+// replayRequest sends the recorded request of a row as it was recorded, with
+// the header overrides, --bearer-token and a new traceparent applied
+func (r *batchRunner) replayRequest(item PostmanItem, record csvRecord, span *Span) RequestResult {
+	startTime := time.Now()
+	result := RequestResult{
+		Timestamp:   startTime,
+		RequestName: item.Name,
+		CSVData:     record.Data,
+		RecordInfo:  fmt.Sprintf("replayed row %d", record.Index),
+		RowIndex:    record.Index,
+		detail:      newRequestDetail(r.config),
+	}
+	fail := func(message string) RequestResult {
+		result.Error = message
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	request, err := recordedRequestOf(record.Data)
+	if err != nil {
+		return fail(fmt.Sprintf("Error reading recorded request: %v", err))
+	}
+	result.Method = request.Method
+	result.URL = request.URL
+	if strings.Contains(request.URL, redactedValue) || strings.Contains(request.Body, redactedValue) {
+		return fail("Recorded request has masked secrets in its URL or body and cannot be replayed")
+	}
+
+	req, err := http.NewRequest(request.Method, request.URL, strings.NewReader(request.Body))
+	if err != nil {
+		return fail(fmt.Sprintf("Error creating request: %v", err))
+	}
+	for name, values := range request.Headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for name, values := range r.replay.headers {
+		req.Header[name] = values
+	}
+	if r.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.config.BearerToken)
+	}
+	if span != nil {
+		req.Header.Set("traceparent", span.Traceparent())
+	}
+
+	// Signatures cover the time they were made and their credentials were masked
+	if scheme := signedAuth(req.Header); scheme != "" {
+		result.AuthError = true
+		return fail(fmt.Sprintf("Auth failed: request was signed with %s auth, which cannot be replayed (send the row again with 'backfill-tool run')", scheme))
+	}
+
+	// Masked credentials were never saved; sending the mask would only be rejected
+	for name, values := range req.Header {
+		for _, value := range values {
+			if strings.Contains(value, redactedValue) {
+				result.AuthError = true
+				return fail(fmt.Sprintf("Auth failed: header %s was masked when recorded (give it with --header or --bearer-token)", name))
+			}
+		}
+	}
+
+	return r.send(item, record, req, request.Body, nil, nil, result)
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chart dimensions for the inline SVG latency charts
+const (
+	chartWidth  = 640
+	chartHeight = 180
+	chartMargin = 30
+)
+
+// reportItem is the per-item view model rendered by the HTML template
+type reportItem struct {
+	ItemMetricsReport
+	StatusBars  []statusBar
+	LatencySVG  template.HTML
+	DataColumns []string
+}
+
+// statusBar is one row of the status code distribution chart
+type statusBar struct {
+	Code    string
+	Count   int64
+	Percent float64
+	Class   string
+}
+
+// GenerateHTMLReport renders a metrics file into a single self-contained HTML page
+func GenerateHTMLReport(metricsFile, outputFile string) error {
+	report, err := LoadMetricsReport(metricsFile)
+	if err != nil {
+		return err
+	}
+	return WriteHTMLReport(report, outputFile)
+}
+
+// WriteHTMLReport renders metrics into a single self-contained HTML page
+func WriteHTMLReport(report *MetricsReport, outputFile string) error {
+	items := make([]reportItem, 0, len(report.Items))
+	for _, item := range report.Items {
+		items = append(items, reportItem{
+			ItemMetricsReport: item,
+			StatusBars:        statusBars(item),
+			LatencySVG:        latencyChart(item.Timeline),
+			DataColumns:       sampleColumns(item.FailedSamples),
+		})
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("error creating report file: %v", err)
+	}
+	defer file.Close()
+
+	data := struct {
+		*MetricsReport
+		ItemViews []reportItem
+	}{report, items}
+
+	if err := reportTemplate.Execute(file, data); err != nil {
+		return fmt.Errorf("error rendering report: %v", err)
+	}
+	return nil
+}
+
+// statusBars returns the status code distribution sorted by code
+func statusBars(item ItemMetricsReport) []statusBar {
+	var total int64
+	for _, count := range item.StatusCodes {
+		total += count
+	}
+
+	bars := make([]statusBar, 0, len(item.StatusCodes))
+	for code, count := range item.StatusCodes {
+		label, class := code, "ok"
+		switch {
+		case code == "0":
+			label, class = "no response", "err"
+		case strings.HasPrefix(code, "4"), strings.HasPrefix(code, "5"):
+			class = "err"
+		case strings.HasPrefix(code, "3"):
+			class = "warn"
+		}
+		bars = append(bars, statusBar{
+			Code:    label,
+			Count:   count,
+			Percent: percentOf(count, total),
+			Class:   class,
+		})
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Code < bars[j].Code })
+	return bars
+}
+
+// sampleColumns returns the sorted union of CSV columns in the failed samples
+func sampleColumns(samples []FailedSample) []string {
+	seen := make(map[string]bool)
+	columns := []string{}
+	for _, sample := range samples {
+		for column := range sample.Data {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// latencyChart draws p50/p90/p99 latency over time as an inline SVG line chart
+func latencyChart(timeline []TimelineBucket) template.HTML {
+	if len(timeline) == 0 {
+		return template.HTML(`<p class="muted">No latency data recorded.</p>`)
+	}
+
+	maxMs := int64(1)
+	maxOffset := timeline[len(timeline)-1].OffsetSeconds
+	for _, bucket := range timeline {
+		if bucket.P99Ms > maxMs {
+			maxMs = bucket.P99Ms
+		}
+	}
+	if maxOffset <= 0 {
+		maxOffset = 1
+	}
+
+	plotW := float64(chartWidth - 2*chartMargin)
+	plotH := float64(chartHeight - 2*chartMargin)
+	points := func(value func(TimelineBucket) int64) string {
+		coords := make([]string, 0, len(timeline))
+		for _, bucket := range timeline {
+			x := chartMargin + bucket.OffsetSeconds/maxOffset*plotW
+			y := chartMargin + plotH - float64(value(bucket))/float64(maxMs)*plotH
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		return strings.Join(coords, " ")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart" role="img" aria-label="Latency percentiles over time">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, chartMargin, chartHeight-chartMargin, chartWidth-chartMargin, chartHeight-chartMargin)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, chartMargin, chartMargin, chartMargin, chartHeight-chartMargin)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="label">%dms</text>`, 2, chartMargin-6, maxMs)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">%.1fs</text>`, chartWidth-chartMargin, chartHeight-8, maxOffset)
+	fmt.Fprintf(&b, `<polyline points="%s" class="p99"/>`, points(func(t TimelineBucket) int64 { return t.P99Ms }))
+	fmt.Fprintf(&b, `<polyline points="%s" class="p90"/>`, points(func(t TimelineBucket) int64 { return t.P90Ms }))
+	fmt.Fprintf(&b, `<polyline points="%s" class="p50"/>`, points(func(t TimelineBucket) int64 { return t.P50Ms }))
+	b.WriteString(`</svg>`)
+	b.WriteString(`<div class="legend"><span class="p50">p50</span> <span class="p90">p90</span> <span class="p99">p99</span></div>`)
+
+	return template.HTML(b.String())
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct": func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+	"rateClass": func(v float64) string {
+		switch {
+		case v >= 99:
+			return "ok"
+		case v >= 90:
+			return "warn"
+		default:
+			return "err"
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Backfill report - {{.CollectionName}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 1100px; color: #222; padding: 0 1rem; }
+h1 { margin-bottom: 0.2rem; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.3rem; margin-top: 2.5rem; }
+.muted { color: #777; }
+.cards { display: flex; gap: 1rem; flex-wrap: wrap; margin: 1.5rem 0; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 0.8rem 1.2rem; min-width: 140px; }
+.card .value { font-size: 1.6rem; font-weight: bold; }
+table { border-collapse: collapse; width: 100%; margin: 0.8rem 0; font-size: 0.9rem; }
+th, td { border: 1px solid #e3e3e3; padding: 0.35rem 0.5rem; text-align: left; vertical-align: top; }
+th { background: #f6f6f6; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.bar { background: #eee; border-radius: 3px; height: 0.9rem; min-width: 120px; }
+.bar div { height: 100%; border-radius: 3px; }
+.ok { color: #1a7f37; } .warn { color: #9a6700; } .err { color: #cf222e; }
+.bar .ok { background: #2da44e; } .bar .warn { background: #d4a72c; } .bar .err { background: #cf222e; }
+.chart { width: 100%; max-width: 640px; height: auto; }
+.chart .axis { stroke: #999; stroke-width: 1; }
+.chart .label { font-size: 10px; fill: #777; }
+.chart polyline { fill: none; stroke-width: 2; }
+.chart .p50 { stroke: #2da44e; } .chart .p90 { stroke: #d4a72c; } .chart .p99 { stroke: #cf222e; }
+.legend span { display: inline-block; padding: 0 0.5rem; font-size: 0.8rem; border-left: 4px solid; }
+.legend .p50 { border-color: #2da44e; } .legend .p90 { border-color: #d4a72c; } .legend .p99 { border-color: #cf222e; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.CollectionName}}</h1>
+<div class="muted">CSV: {{.CSVFile}} &middot; {{.StartTime}} &rarr; {{.EndTime}} ({{printf "%.1f" .DurationSeconds}}s)</div>
+
+<div class="cards">
+  <div class="card"><div class="muted">Requests</div><div class="value">{{.Summary.TotalRequests}}</div></div>
+  <div class="card"><div class="muted">Successful</div><div class="value ok">{{.Summary.Successful}}</div></div>
+  <div class="card"><div class="muted">Failed</div><div class="value err">{{.Summary.Failed}}</div></div>
+  {{if .Summary.Skipped}}<div class="card"><div class="muted">Skipped (canary abort)</div><div class="value err">{{.Summary.Skipped}}</div></div>{{end}}
+  <div class="card"><div class="muted">Success rate</div><div class="value {{rateClass .Summary.SuccessRatePct}}">{{pct .Summary.SuccessRatePct}}%</div></div>
+  <div class="card"><div class="muted">Records</div><div class="value">{{.TotalRecords}}</div></div>
+</div>
+
+<h2>Items</h2>
+<table>
+<tr><th>Item</th><th>Requests</th><th>Success</th><th>Failed</th><th>Success rate</th><th>Avg</th><th>p50</th><th>p90</th><th>p99</th><th>Max</th></tr>
+{{range .ItemViews}}
+<tr>
+  <td><a href="#{{.Name}}">{{.Name}}</a>{{if .Skipped}} <span class="err">({{.Skipped}} skipped)</span>{{end}}</td>
+  <td class="num">{{.TotalRequests}}</td>
+  <td class="num">{{.Successful}}</td>
+  <td class="num">{{.Failed}}</td>
+  <td><div class="bar"><div class="{{rateClass .SuccessRatePct}}" style="width: {{pct .SuccessRatePct}}%"></div></div>{{pct .SuccessRatePct}}%</td>
+  <td class="num">{{.Timing.AvgMs}}ms</td>
+  <td class="num">{{.Timing.P50Ms}}ms</td>
+  <td class="num">{{.Timing.P90Ms}}ms</td>
+  <td class="num">{{.Timing.P99Ms}}ms</td>
+  <td class="num">{{.Timing.MaxMs}}ms</td>
+</tr>
+{{end}}
+</table>
+
+{{range .ItemViews}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+<div class="muted">{{.TotalRequests}} requests in {{printf "%.1f" .DurationSeconds}}s</div>
+
+<h3>Latency over time</h3>
+{{.LatencySVG}}
+
+<h3>Status codes</h3>
+<table>
+<tr><th>Status</th><th>Count</th><th>Share</th></tr>
+{{range .StatusBars}}
+<tr><td class="{{.Class}}">{{.Code}}</td><td class="num">{{.Count}}</td><td><div class="bar"><div class="{{.Class}}" style="width: {{pct .Percent}}%"></div></div>{{pct .Percent}}%</td></tr>
+{{end}}
+</table>
+
+{{if .TopErrors}}
+<h3>Top errors</h3>
+<table>
+<tr><th>Count</th><th>Error</th></tr>
+{{range .TopErrors}}<tr><td class="num">{{.Count}}</td><td><code>{{.Message}}</code></td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{if .FailedSamples}}
+<h3>Sample of failing rows</h3>
+{{$columns := .DataColumns}}
+<table>
+<tr><th>Row</th><th>Status</th>{{range $columns}}<th>{{.}}</th>{{end}}<th>URL</th><th>Error</th></tr>
+{{range .FailedSamples}}{{$data := .Data}}
+<tr><td class="num">{{.Row}}</td><td class="err">{{.StatusCode}}</td>{{range $columns}}<td>{{index $data .}}</td>{{end}}<td><code>{{.Method}} {{.URL}}</code></td><td><code>{{.Error}}</code></td></tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+
+<p class="muted">Generated by backfill-tool</p>
+</body>
+</html>
+`))
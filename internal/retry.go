@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retry defaults used when the corresponding RunConfig field is zero
+const (
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// defaultRetryStatuses are retried when RunConfig.RetryOn is empty
+var defaultRetryStatuses = []int{429, 502, 503, 504}
+
+// shouldRetry reports whether a failed attempt is worth repeating: transport
+// errors and the configured status codes are, everything else is final
+func (r *batchRunner) shouldRetry(result RequestResult) bool {
+	if result.Success || r.config.DryRun {
+		return false
+	}
+	if result.NetworkError {
+		return true
+	}
+	statuses := r.config.RetryOn
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	for _, status := range statuses {
+		if result.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// executeWithRetries sends a row, retrying transient failures with exponential
+// backoff and jitter. Each attempt waits for the run's rate limiter. The row's
+// span starts once the first attempt may be sent, so it leaves out queueing.
+func (r *batchRunner) executeWithRetries(item PostmanItem, record csvRecord) (RequestResult, *Span) {
+	var span *Span
+	for attempt := 0; ; attempt++ {
+		r.limiter.Wait()
+		if attempt == 0 {
+			span = r.startSpan(item, record)
+		}
+		result := r.executeRequest(item, record, span)
+		result.Retries = attempt
+		r.printVerbose(item, record, result)
+		if attempt >= r.config.Retries || !r.shouldRetry(result) {
+			return result, span
+		}
+		delay := retryDelay(attempt, result.RetryAfter, r.config.RetryBackoff, r.config.RetryMaxBackoff)
+		logger.Debug("retrying request", "item", item.Name, "row", record.Index, "attempt", attempt+1,
+			"status", result.StatusCode, "delay_ms", delay.Milliseconds())
+		time.Sleep(delay)
+	}
+}
+
+// retryDelay returns how long to wait before the next attempt. A server supplied
+// Retry-After wins over the computed backoff, both are capped at maxBackoff.
+func retryDelay(attempt int, retryAfter, backoff, maxBackoff time.Duration) time.Duration {
+	backoff = durationOrDefault(backoff, defaultRetryBackoff)
+	maxBackoff = durationOrDefault(maxBackoff, defaultRetryMaxBackoff)
+
+	if retryAfter > 0 {
+		return min(retryAfter, maxBackoff)
+	}
+
+	delay := backoff << min(attempt, 20)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+	// Equal jitter: keep half the delay, randomize the other half
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// rateLimiter is a token bucket shared by all workers of a run
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing requestsPerSecond with bursts of up
+// to burst requests, or nil (no limit) when requestsPerSecond is not positive
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent. Tokens are reserved before
+// sleeping, so waiting workers are released in arrival order.
+func (l *rateLimiter) Wait() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+}
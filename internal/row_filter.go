@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// rowFilter selects the CSV rows a run sends. Filters apply in a fixed order:
+// --rows range, --where expressions, --sample, then --skip and --limit.
+type rowFilter struct {
+	first, last int // --rows bounds as 1-based data row numbers, inclusive; 0 = open
+	where       []whereNode
+	sample      float64 // Fraction of rows kept; 0 keeps all
+	seed        uint64
+	skip, limit int
+	report      RowFilterReport
+}
+
+// RowFilterReport records the row filters of a run and how many rows each removed
+type RowFilterReport struct {
+	Rows   string   `json:"rows,omitempty"`
+	Where  []string `json:"where,omitempty"`
+	Sample string   `json:"sample,omitempty"`
+	Seed   uint64   `json:"seed,omitempty"`
+	Skip   int      `json:"skip,omitempty"`
+	Limit  int      `json:"limit,omitempty"`
+
+	CSVRows         int `json:"csv_rows"`
+	Selected        int `json:"selected"`
+	OutsideRange    int `json:"outside_range"`
+	FilteredByWhere int `json:"filtered_by_where"`
+	NotSampled      int `json:"not_sampled"`
+	Skipped         int `json:"skipped"`
+	OverLimit       int `json:"over_limit"`
+}
+
+// newRowFilter parses the row filter settings of a run, or returns nil when none
+// are set. columns are the CSV headers --where expressions may reference.
+func newRowFilter(config RunConfig, columns map[string]bool) (*rowFilter, error) {
+	if config.Rows == "" && len(config.Where) == 0 && config.Sample == "" && config.Skip == 0 && config.Limit == 0 {
+		return nil, nil
+	}
+	if config.Skip < 0 || config.Limit < 0 {
+		return nil, fmt.Errorf("--skip and --limit must not be negative")
+	}
+
+	f := &rowFilter{skip: config.Skip, limit: config.Limit}
+	f.report = RowFilterReport{Rows: config.Rows, Where: config.Where, Sample: config.Sample, Skip: config.Skip, Limit: config.Limit}
+
+	if config.Rows != "" {
+		var err error
+		if f.first, f.last, err = parseRowRange(config.Rows); err != nil {
+			return nil, err
+		}
+	}
+	for _, expr := range config.Where {
+		node, err := parseWhere(expr, columns)
+		if err != nil {
+			return nil, err
+		}
+		f.where = append(f.where, node)
+	}
+	if config.Sample != "" {
+		var err error
+		if f.sample, err = parseSampleRate(config.Sample); err != nil {
+			return nil, err
+		}
+		// Without a seed each run samples differently; the seed used is reported so it can be repeated
+		f.seed = uint64(config.SampleSeed)
+		if f.seed == 0 {
+			f.seed = uint64(rand.Int64())
+		}
+		f.report.Seed = f.seed
+	}
+	return f, nil
+}
+
+// parseRowRange parses "100:200", "100:", ":200" or a single row number "150"
+func parseRowRange(spec string) (int, int, error) {
+	invalid := fmt.Errorf("invalid --rows %q (expected FIRST:LAST with 1-based row numbers, either may be omitted)", spec)
+	bound := func(s string) (int, error) {
+		if s = strings.TrimSpace(s); s == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, invalid
+		}
+		return n, nil
+	}
+
+	firstSpec, lastSpec, isRange := strings.Cut(spec, ":")
+	first, err := bound(firstSpec)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		if first == 0 {
+			return 0, 0, invalid
+		}
+		return first, first, nil
+	}
+	last, err := bound(lastSpec)
+	if err != nil {
+		return 0, 0, err
+	}
+	if last != 0 && last < first {
+		return 0, 0, invalid
+	}
+	return first, last, nil
+}
+
+// parseSampleRate parses "1%", "0.5%" or a fraction like "0.01"
+func parseSampleRate(spec string) (float64, error) {
+	value := strings.TrimSpace(spec)
+	percent := strings.HasSuffix(value, "%")
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if percent {
+		rate /= 100
+	}
+	if err != nil || rate <= 0 || rate > 1 {
+		return 0, fmt.Errorf("invalid --sample %q (expected a percentage like 1%% or a fraction like 0.01)", spec)
+	}
+	return rate, nil
+}
+
+// apply returns the records that pass every filter, in file order
+func (f *rowFilter) apply(records []csvRecord) []csvRecord {
+	f.report.CSVRows = len(records)
+	var kept []csvRecord
+	for _, record := range records {
+		switch {
+		case f.first > 0 && record.Index < f.first, f.last > 0 && record.Index > f.last:
+			f.report.OutsideRange++
+		case !f.matches(record.Data):
+			f.report.FilteredByWhere++
+		case f.sample > 0 && !f.sampled(record.Index):
+			f.report.NotSampled++
+		case f.report.Skipped < f.skip:
+			f.report.Skipped++
+		case f.limit > 0 && len(kept) >= f.limit:
+			f.report.OverLimit++
+		default:
+			kept = append(kept, record)
+		}
+	}
+	f.report.Selected = len(kept)
+	return kept
+}
+
+func (f *rowFilter) matches(row map[string]string) bool {
+	for _, node := range f.where {
+		if !node.eval(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// sampled decides per row from the seed and row number alone, so a seed always
+// picks the same rows of a file whatever the other filters are
+func (f *rowFilter) sampled(index int) bool {
+	return float64(splitMix64(f.seed^splitMix64(uint64(index)))>>11)/(1<<53) < f.sample
+}
+
+// splitMix64 is the SplitMix64 finalizer, a fast well-mixed 64-bit hash
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
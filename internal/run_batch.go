@@ -2,14 +2,19 @@ package internal
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -31,14 +36,117 @@ const (
 
 // RunConfig contains all configuration for a batch run
 type RunConfig struct {
-	BatchSize    int
-	Threads      int
-	Collection   string
-	CSV          string
-	MetricsFile  string
-	Verbose      bool
-	Quiet        bool
-	BearerToken  string // CLI override for bearer token
+	BatchSize   int
+	Threads     int
+	Collection  string
+	CSV         string
+	MetricsFile string
+	HTMLReport  string // Optional path for a self-contained HTML report
+	JUnitFile   string // Optional path for a JUnit XML report
+	Verbose     int    // 0 (off) to VerboseBodies
+	Quiet       bool
+	BearerToken string // CLI override for bearer token
+
+	VerboseFailures bool // Verbose output only for failed requests
+
+	// Structured log (off unless LogFormat or LogFile is set)
+	LogFormat string // LogFormatText (default) or LogFormatJSON
+	LogFile   string // Appended to; stderr when empty
+
+	SerializeBy []string // Rows sharing these column values are sent one at a time, in CSV order
+
+	// Parallel items (ParallelItems 0 or 1 runs items one after another)
+	ParallelItems int    // Items running at the same time
+	ParallelScope string // ParallelScopeItem (default) or ParallelScopeFolder
+	WorkerBudget  string // WorkerBudgetShared (default) or WorkerBudgetPerItem
+
+	// HTTP client tuning (zero values use the transport defaults)
+	RequestTimeout        time.Duration // Overall per-request timeout
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration // Zero waits as long as RequestTimeout allows
+	IdleConnTimeout       time.Duration
+	MaxIdleConnsPerHost   int // Defaults to Threads
+	DisableKeepAlives     bool
+	HTTPVersion           string // "", "1.1", "2" or "h2c"
+
+	// TLS
+	CACertFile         string // PEM bundle trusted in addition to the system roots
+	ClientCertFile     string // Default client certificate (mTLS)
+	ClientKeyFile      string
+	HostClientCerts    []string // Per-host client certificates: "host=cert.pem:key.pem"
+	TLSMinVersion      string   // "1.0" to "1.3"
+	TLSServerName      string   // SNI / verification name override
+	InsecureSkipVerify bool
+
+	// Proxy
+	Proxy   string   // http://, https://, socks5:// or socks5h:// URL, optionally with user:pass@
+	NoProxy []string // Hosts, domains, IPs and CIDRs that bypass Proxy
+
+	DryRun bool // Render requests and print them without sending anything
+
+	// Redaction (built-in rules always apply)
+	RedactPatterns []string // Regular expressions masked in all output
+	RedactColumns  []string // CSV columns whose values are masked in all output
+
+	VaultFile string // Encrypted vault for {{secret:vault:NAME}} references (default: DefaultVaultPath)
+
+	// Retries and rate limiting
+	Retries         int           // Extra attempts for transport errors and RetryOn statuses
+	RetryBackoff    time.Duration // Delay before the first retry, doubled for each further one
+	RetryMaxBackoff time.Duration
+	RetryOn         []int   // Retried status codes (default 429, 502, 503, 504)
+	RateLimit       float64 // Requests per second across all workers (0 = unlimited)
+	RateLimitBurst  int
+
+	// Inputs
+	Variables     map[string]string // Static template variables; CSV columns win on conflicts
+	VariableFiles []string          // Postman environment, JSON, YAML or .env files, applied in order
+	Items         []string          // Request names, paths ("Folder/Item") or globs to run (default: all)
+	Folders       []string          // Folders whose requests run
+	ExcludeItems  []string          // Requests or folders skipped
+	AuthOverride  *PostmanAuth      // Replaces collection and request auth (below BearerToken)
+
+	// Deduplication, applied to the whole file before the row filters
+	DedupeBy   []string // Key columns; rows repeating a key are dropped (empty = no deduplication)
+	DedupeKeep string   // DedupeKeepFirst (default) or DedupeKeepLast
+	DedupeMode string   // DedupeModeMemory (default), DedupeModeDisk or DedupeModeApproximate
+
+	// Row filters, applied in this order (all empty = every row)
+	Rows       string   // 1-based inclusive data row range "FIRST:LAST"
+	Where      []string // Expressions over CSV columns, all must hold
+	Sample     string   // "1%" or "0.01" of the remaining rows
+	SampleSeed int64    // Repeats a sample; 0 picks a random seed
+	Skip       int
+	Limit      int
+
+	// Canary phase (disabled when CanaryRows is 0)
+	CanaryRows       int           // Rows of each item sent before the rest
+	CanaryRandom     bool          // Random rows (seeded by SampleSeed) instead of the first ones
+	CanaryMinSuccess float64       // Success rate in percent the canary must reach
+	CanaryMaxP95     time.Duration // p95 latency the canary must stay under (0 = not checked)
+	CanaryDecision   string        // CanaryDecisionAuto (default) or CanaryDecisionPrompt
+
+	RunID string // Identifies the run in metrics and idempotency keys (default: generated)
+
+	// Idempotency keys
+	IdempotencyKeys    bool     // Send a key derived from run ID, item and row with every request
+	IdempotencyHeader  string   // Default DefaultIdempotencyHeader
+	IdempotencyColumns []string // Identify rows by these columns instead of their row number
+
+	OutputDir      string // Directory for failed request CSVs and the default metrics file
+	RecordRequests bool   // Save the masked requests sent, for replay (failed request CSVs and the run log)
+
+	// Replay (see Replay)
+	ReplayHeaders    []string // "Name: value" headers replacing recorded ones
+	ReplayFailedOnly bool     // Only replay the failed requests of a run log
+
+	// Tracing (disabled when both destinations are empty)
+	TraceEndpoint    string // OTLP/HTTP collector base URL, e.g. http://localhost:4318
+	TraceFile        string // File to append OTLP/JSON trace batches to
+	TraceServiceName string
+
+	fileKeys map[string]bool // Keys set in the config file this was loaded from (see Has)
 }
 
 // PostmanCollection represents the top-level structure of a Postman collection JSON file
@@ -93,10 +201,14 @@ type PostmanHeader struct {
 // PostmanAuth represents authentication configuration in Postman
 // Supports both array format and object format for compatibility
 type PostmanAuth struct {
-	Type      string          `json:"type"` // "bearer", "apikey", "basic", etc.
+	Type      string          `json:"type"` // "bearer", "apikey", "basic", "oauth2", "awsv4", "digest", "hawk" or "noauth"
 	BearerRaw json.RawMessage `json:"bearer,omitempty"`
 	APIKeyRaw json.RawMessage `json:"apikey,omitempty"`
 	BasicRaw  json.RawMessage `json:"basic,omitempty"`
+	OAuth2Raw json.RawMessage `json:"oauth2,omitempty"`
+	AWSv4Raw  json.RawMessage `json:"awsv4,omitempty"`
+	DigestRaw json.RawMessage `json:"digest,omitempty"`
+	HawkRaw   json.RawMessage `json:"hawk,omitempty"`
 }
 
 // PostmanKV represents key-value pairs in auth configuration (array format)
@@ -108,17 +220,37 @@ type PostmanKV struct {
 
 // RequestResult represents the outcome of a single HTTP request
 type RequestResult struct {
-	Success       bool
-	StatusCode    int
-	ResponseTime  time.Duration
-	Message       string
-	RecordInfo    string
-	Error         string
-	URL           string
-	Method        string
-	CSVData       map[string]string
-	RequestName   string
-	Timestamp     time.Time
+	Success        bool
+	StatusCode     int
+	ResponseTime   time.Duration
+	Message        string
+	RecordInfo     string
+	Error          string
+	URL            string
+	Method         string
+	CSVData        map[string]string
+	RequestName    string
+	Timestamp      time.Time
+	RowIndex       int           // 1-based data row number in the CSV
+	TraceID        string        // W3C trace ID sent in the traceparent header (tracing only)
+	IdempotencyKey string        // Idempotency key sent with the request (idempotency keys only)
+	ConnReused     bool          // Whether the request was sent on a pooled keep-alive connection
+	Protocol       string        // Negotiated protocol of the response, e.g. "HTTP/2.0"
+	TLSHandshake   time.Duration // Time spent in the TLS handshake (zero for reused connections)
+	AuthError      bool          // The request was not sent because credentials could not be obtained
+	NetworkError   bool          // No response was received (connection, timeout or protocol error)
+	Retries        int           // Attempts made before this result, which is the last one
+	RetryAfter     time.Duration // Delay requested by the server's Retry-After header
+
+	Request *RecordedRequest // The request as sent, with secrets masked (--record-requests only)
+
+	detail *requestDetail // Rendered request and response (nil unless --verbose or --record-requests needs them)
+}
+
+// csvRecord is a CSV data row together with its position in the file
+type csvRecord struct {
+	Index int
+	Data  map[string]string
 }
 
 // RequestMetrics tracks statistics for a request or collection item
@@ -133,195 +265,430 @@ type RequestMetrics struct {
 	StartTime      time.Time
 	EndTime        time.Time
 	FailedRequests []RequestResult
+	StatusCodes    map[int]int64    // Response count per status code (0 = no response)
+	Latencies      []LatencySample  // Every response time, in completion order
+	NewConns       int64            // Requests that had to open a new connection
+	ReusedConns    int64            // Requests served by an idle pooled connection
+	Protocols      map[string]int64 // Response count per negotiated protocol
+	TLSHandshakes  int64            // Number of TLS handshakes performed
+	TLSTime        time.Duration    // Total time spent in TLS handshakes
+	AuthFailures   int64            // Rows not sent because obtaining credentials failed
+	Retries        int64            // Extra attempts made for transient failures
+	Skipped        int64            // Rows not sent because a canary stopped the run
 }
 
 // RunMetrics tracks overall execution metrics
 type RunMetrics struct {
+	RunID          string
 	CollectionName string
 	CSVFile        string
 	StartTime      time.Time
 	EndTime        time.Time
 	TotalRecords   int
 	ItemMetrics    []RequestMetrics
-}
-
-// ProgressTracker manages real-time progress display
-type ProgressTracker struct {
-	total       int64
-	current     int64
-	success     int64
-	failure     int64
-	startTime   time.Time
-	quiet       bool
-	mu          sync.Mutex
-	lastPrint   time.Time
-	description string
-}
-
-// NewProgressTracker creates a new progress tracker
-func NewProgressTracker(total int, description string, quiet bool) *ProgressTracker {
-	return &ProgressTracker{
-		total:       int64(total),
-		current:     0,
-		success:     0,
-		failure:     0,
-		startTime:   time.Now(),
-		quiet:       quiet,
-		lastPrint:   time.Now(),
-		description: description,
-	}
-}
 
-// Update increments progress and updates display
-func (p *ProgressTracker) Update(success bool) {
-	atomic.AddInt64(&p.current, 1)
-	if success {
-		atomic.AddInt64(&p.success, 1)
-	} else {
-		atomic.AddInt64(&p.failure, 1)
-	}
+	TokenFetches       int64 // OAuth2 token requests made
+	TokenFetchFailures int64
 
-	if !p.quiet {
-		p.mu.Lock()
-		// Update display every 100ms to avoid flickering
-		if time.Since(p.lastPrint) > 100*time.Millisecond {
-			p.display()
-			p.lastPrint = time.Now()
-		}
-		p.mu.Unlock()
-	}
+	Selection *SelectionReport // Nil when every request ran
+	Dedupe    *DedupeReport    // Nil without --dedupe-by
+	Rows      *RowFilterReport // Nil when every CSV row was sent
+	Canary    *CanaryReport    // Nil without a canary phase
 }
 
-// Finish completes the progress display
-func (p *ProgressTracker) Finish() {
-	if !p.quiet {
-		p.mu.Lock()
-		p.display()
-		fmt.Println() // New line after progress
-		p.mu.Unlock()
-	}
-}
-
-// display renders the progress bar
-func (p *ProgressTracker) display() {
-	current := atomic.LoadInt64(&p.current)
-	success := atomic.LoadInt64(&p.success)
-	failure := atomic.LoadInt64(&p.failure)
-
-	percent := float64(current) / float64(p.total) * 100
-	elapsed := time.Since(p.startTime)
-	avgTime := elapsed / time.Duration(current+1)
-	eta := avgTime * time.Duration(p.total-current)
-
-	// Create progress bar (40 characters wide)
-	barWidth := 40
-	filled := int(float64(barWidth) * percent / 100)
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
-
-	// Format output with colors
-	fmt.Printf("\r%sProgress:%s [%s] %d/%d (%.1f%%) | %s✓%d%s %s✗%d%s | Avg: %dms | ETA: %s  ",
-		colorBold, colorReset,
-		bar,
-		current, p.total, percent,
-		colorGreen, success, colorReset,
-		colorRed, failure, colorReset,
-		avgTime.Milliseconds(),
-		formatDuration(eta))
-}
-
-// formatDuration formats duration for display
-func formatDuration(d time.Duration) string {
-	if d < time.Second {
-		return "0s"
-	}
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
-	}
-	minutes := int(d.Minutes())
-	seconds := int(d.Seconds()) % 60
-	return fmt.Sprintf("%dm%ds", minutes, seconds)
-}
-
-// colorize applies color to text
+// colorize applies color to text when colors are enabled
 func colorize(color, text string) string {
+	if !colorEnabled {
+		return text
+	}
 	return color + text + colorReset
 }
 
-// RunBatch is the main entry point for processing a Postman collection with CSV data
-func RunBatch(config RunConfig) {
+// RunBatch is the main entry point for processing a Postman collection with CSV data.
+// It reports whether a canary aborted the run.
+func RunBatch(config RunConfig) (aborted bool) {
 	startTime := time.Now()
 
+	closeLog, err := setupLogger(config)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	defer closeLog()
+
 	// Validate input parameters
 	if config.Collection == "" {
-		fmt.Println(colorize(colorRed, "Error: Collection file path is required"))
+		printError("Error: Collection file path is required")
 		return
 	}
 	if config.CSV == "" {
-		fmt.Println(colorize(colorRed, "Error: CSV file path is required"))
+		printError("Error: CSV file path is required")
 		return
 	}
 	if config.Threads <= 0 {
-		fmt.Println(colorize(colorRed, "Error: Number of threads must be greater than 0"))
+		printError("Error: Number of threads must be greater than 0")
+		return
+	}
+
+	// Secret references are resolved once here; only the resolver ever sees the values
+	secrets := newSecretResolver(config.VaultFile)
+	bearerToken, err := secrets.ResolveString(config.BearerToken)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
 		return
 	}
+	config.BearerToken = bearerToken
 
 	// Load and parse the Postman collection
-	jsonFile, err := os.Open(config.Collection)
+	collectionJSON, err := os.ReadFile(config.Collection)
 	if err != nil {
-		fmt.Printf("%s\n", colorize(colorRed, fmt.Sprintf("Error opening collection file '%s': %v", config.Collection, err)))
+		printError(fmt.Sprintf("Error opening collection file '%s': %v", config.Collection, err))
+		return
+	}
+	if collectionJSON, err = secrets.ResolveJSON(collectionJSON); err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
 		return
 	}
-	defer jsonFile.Close()
 
 	var postmanCollection PostmanCollection
-	if err := json.NewDecoder(jsonFile).Decode(&postmanCollection); err != nil {
-		fmt.Printf("%s\n", colorize(colorRed, fmt.Sprintf("Error parsing collection JSON: %v", err)))
+	if err := json.Unmarshal(collectionJSON, &postmanCollection); err != nil {
+		printError(fmt.Sprintf("Error parsing collection JSON: %v", err))
+		return
+	}
+
+	// An auth override from the run configuration replaces every auth block of the collection
+	if config.AuthOverride != nil {
+		overrideJSON, _ := json.Marshal(config.AuthOverride)
+		if overrideJSON, err = secrets.ResolveJSON(overrideJSON); err != nil {
+			printError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		override := &PostmanAuth{}
+		json.Unmarshal(overrideJSON, override)
+		overrideAuth(&postmanCollection, override)
+	}
+
+	selector, err := newItemSelector(config.Items, config.Folders, config.ExcludeItems)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
 		return
 	}
+	var selection *SelectionReport
+	if selector.active() {
+		selection = selector.report(postmanCollection.Item)
+		if len(selection.Selected) == 0 {
+			printError("Error: No requests match the --item, --folder and --exclude selection (see 'backfill-tool list')")
+			return
+		}
+	}
+
+	// Refuse to start rather than send unauthenticated requests; --bearer-token replaces all auth
+	if config.BearerToken == "" {
+		if err := validateAuthTypes(postmanCollection); err != nil {
+			printError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+	}
 
 	if !config.Quiet {
-		fmt.Printf("%s\n", colorize(colorCyan+colorBold, "📦 Collection: "+postmanCollection.Info.Name))
-		fmt.Printf("📊 Items found: %s\n", colorize(colorYellow, fmt.Sprintf("%d", len(postmanCollection.Item))))
+		screen.Printf("%s\n", colorize(colorCyan+colorBold, "📦 Collection: "+postmanCollection.Info.Name))
+		screen.Printf("📊 Items found: %s\n", colorize(colorYellow, fmt.Sprintf("%d", len(postmanCollection.Item))))
+		if selection != nil {
+			screen.Printf("🎯 Selected: %s\n", colorize(colorYellow, fmt.Sprintf("%d of %d requests", len(selection.Selected), len(selection.Selected)+len(selection.Skipped))))
+		}
 	}
 
-	// Read CSV data once and reuse for all requests
+	deduper, err := newRowDeduper(config)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	resolveRow := func(row map[string]string) (err error) {
+		for column, value := range row {
+			if row[column], err = secrets.ResolveString(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Read CSV data once and reuse for all requests. With --dedupe-by the file
+	// is streamed through the deduper, so duplicate rows are never held in memory;
+	// duplicates are removed from the whole file first, so row filters and
+	// resumed --rows ranges see the same rows whatever they select.
 	if !config.Quiet {
-		fmt.Printf("📂 Reading CSV file: %s\n", config.CSV)
+		screen.Printf("📂 Reading CSV file: %s\n", config.CSV)
+	}
+	var headers []string
+	var records []csvRecord
+	var duplicates []dedupeDuplicate
+	if deduper != nil {
+		headers, records, duplicates, err = deduper.read(config.CSV, resolveRow)
+	} else {
+		headers, err = streamCSV(config.CSV, func(row map[string]string) error {
+			if err := resolveRow(row); err != nil {
+				return err
+			}
+			records = append(records, csvRecord{Index: len(records) + 1, Data: row})
+			return nil
+		})
 	}
-	requestList, err := ReadCSV(config.CSV)
 	if err != nil {
-		fmt.Printf("%s\n", colorize(colorRed, fmt.Sprintf("Error reading CSV file: %v", err)))
+		printError(fmt.Sprintf("Error reading CSV file: %v", err))
 		return
 	}
+	csvRows := len(records) + len(duplicates)
+
+	variables, err := loadVariables(config.VariableFiles, config.Variables)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	for name, value := range variables {
+		if variables[name], err = secrets.ResolveString(value); err != nil {
+			printError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+	}
 
 	if !config.Quiet {
-		fmt.Printf("%s\n\n", colorize(colorGreen, fmt.Sprintf("✓ Loaded %d records from CSV", len(requestList))))
+		screen.Printf("%s\n\n", colorize(colorGreen, fmt.Sprintf("✓ Loaded %d records from CSV", csvRows)))
 	}
 
-	if len(requestList) == 0 {
-		fmt.Println(colorize(colorYellow, "Warning: No data records found in CSV file (only headers)"))
+	if config.OutputDir != "" && !config.DryRun {
+		if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
+			printError(fmt.Sprintf("Error creating output directory: %v", err))
+			return
+		}
+	}
+
+	if csvRows == 0 {
+		printWarning("Warning: No data records found in CSV file (only headers)")
 		return
 	}
 
+	columns := make(map[string]bool)
+	for _, column := range headers {
+		columns[column] = true
+	}
+	filter, err := newRowFilter(config, columns)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if err := validateParallel(config); err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	serializer, err := newRowSerializer(config, columns)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	runID := config.RunID
+	if runID == "" {
+		runID = newRunID()
+	}
+	// A failed request CSV with keys is re-sent with the same keys
+	if columns[idempotencyKeyColumn] {
+		config.IdempotencyKeys = true
+	}
+	idempotency, err := newIdempotencyKeys(config, runID, columns)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	logger = logger.With("run_id", runID)
+	logger.Info("run started", "collection", postmanCollection.Info.Name, "csv", config.CSV, "records", csvRows,
+		"threads", config.Threads, "dry_run", config.DryRun)
+	if idempotency != nil && !config.Quiet {
+		screen.Printf("🔑 Idempotency keys for run %s (repeat with --run-id %s)\n\n", runID, runID)
+	}
+	if deduper != nil {
+		logger.Info("rows deduplicated", "columns", deduper.columns, "keep", deduper.report.Keep, "duplicates", len(duplicates), "kept", len(records))
+		if !config.Quiet {
+			screen.Printf("🧹 Removed %s by %s (keeping the %s)\n\n",
+				colorize(colorYellow, fmt.Sprintf("%d duplicate rows", len(duplicates))), strings.Join(deduper.columns, ", "), deduper.report.Keep)
+		}
+	}
+	if filter != nil {
+		records = filter.apply(records)
+		logger.Info("rows selected", "selected", len(records), "csv_rows", csvRows)
+		if !config.Quiet {
+			screen.Printf("%s\n\n", colorize(colorGreen, fmt.Sprintf("✓ Selected %d of %d records", len(records), csvRows)))
+		}
+		if filter.report.Seed != 0 && !config.Quiet {
+			screen.Printf("🎲 Sample seed: %d (repeat with --seed %d)\n\n", filter.report.Seed, filter.report.Seed)
+		}
+		if len(records) == 0 {
+			printWarning("Warning: No records left after applying the row filters")
+			return
+		}
+	}
+
 	// Initialize run metrics
 	runMetrics := &RunMetrics{
+		RunID:          runID,
 		CollectionName: postmanCollection.Info.Name,
 		CSVFile:        config.CSV,
 		StartTime:      startTime,
-		TotalRecords:   len(requestList),
+		TotalRecords:   len(records),
 		ItemMetrics:    []RequestMetrics{},
+		Selection:      selection,
+	}
+	if deduper != nil {
+		runMetrics.Dedupe = &deduper.report
+	}
+	if filter != nil {
+		runMetrics.Rows = &filter.report
 	}
 
-	// Process all items in the collection recursively
-	for _, item := range postmanCollection.Item {
-		processItem(item, requestList, config, runMetrics, 0, postmanCollection.Auth)
+	redactor, err := newRedactor(config.RedactPatterns, config.RedactColumns)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
 	}
+	redactor.references = secrets.References()
 
+	if len(duplicates) > 0 && !config.DryRun {
+		if deduper.report.ReportFile, err = saveDuplicates(config.CSV, headers, duplicates, config.OutputDir, redactor); err != nil {
+			printWarning(fmt.Sprintf("Warning: Failed to write the duplicates report: %v", err))
+		} else if !config.Quiet {
+			screen.Printf("%s\n\n", colorize(colorGreen, "🧹 Duplicate rows saved to: "+deduper.report.ReportFile))
+		}
+	}
+
+	proxy, err := newProxyFunc(config)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	client, err := newHTTPClient(config, proxy)
+	if err != nil {
+		printError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if config.InsecureSkipVerify {
+		printInsecureWarning()
+	}
+
+	// A dry run sends nothing, so there is no canary to evaluate
+	var canary *canaryPhase
+	if !config.DryRun {
+		if canary, err = newCanaryPhase(config); err != nil {
+			printError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+	}
+	if canary != nil {
+		runMetrics.Canary = canary.report
+		if canary.random && !config.Quiet {
+			screen.Printf("🎲 Canary seed: %d (repeat with --seed %d)\n\n", canary.seed, canary.seed)
+		}
+	}
+
+	// Nothing is sent in a dry run, so there is nothing to trace
+	var tracer *Tracer
+	if !config.DryRun {
+		tracer, err = NewTracer(config.TraceServiceName, config.TraceEndpoint, config.TraceFile)
+		if err != nil {
+			printError(fmt.Sprintf("Error initializing tracing: %v", err))
+			return
+		}
+	}
+
+	runner := &batchRunner{
+		config:         config,
+		collectionAuth: postmanCollection.Auth,
+		tracer:         tracer,
+		client:         client,
+		proxy:          proxy,
+		tokens:         newTokenCache(client),
+		digests:        newDigestCache(),
+		awsCredentials: sync.OnceValues(defaultAWSCredentials),
+		redactor:       redactor,
+		limiter:        newRateLimiter(config.RateLimit, config.RateLimitBurst),
+		variables:      variables,
+		selector:       selector,
+		serializer:     serializer,
+		canary:         canary,
+		idempotency:    idempotency,
+		budget:         newWorkerBudget(config),
+	}
+
+	if runner.parallel() && !config.Quiet {
+		budget := fmt.Sprintf("%d requests in flight across all items", config.Threads)
+		if runner.budget == nil {
+			budget = fmt.Sprintf("%d workers per item", config.Threads)
+		}
+		scope := "items"
+		if config.ParallelScope == ParallelScopeFolder {
+			scope = "top-level folders"
+		}
+		screen.Printf("⚡ Running up to %d %s at a time (%s)\n\n", config.ParallelItems, scope, budget)
+	}
+
+	// Process all items in the collection recursively
+	runner.processItems(postmanCollection.Item, func(string) []csvRecord { return records }, runMetrics)
+
+	runner.finishRun(runMetrics)
+	return runner.aborted.Load()
+}
+
+// finishRun records the end of a run, then writes its outputs unless it was a dry run
+func (r *batchRunner) finishRun(runMetrics *RunMetrics) {
+	config := r.config
 	runMetrics.EndTime = time.Now()
+	runMetrics.TokenFetches, runMetrics.TokenFetchFailures = r.tokens.Stats()
+
+	var sent, failed int64
+	for _, item := range runMetrics.ItemMetrics {
+		sent += item.SuccessCount + item.FailureCount
+		failed += item.FailureCount
+	}
+	logger.Info("run finished", "items", len(runMetrics.ItemMetrics), "requests", sent, "failed", failed,
+		"aborted", r.aborted.Load(), "duration_ms", runMetrics.EndTime.Sub(runMetrics.StartTime).Milliseconds())
+
+	if r.tracer != nil {
+		if err := r.tracer.Shutdown(); err != nil && !config.Quiet {
+			printWarning(fmt.Sprintf("Warning: %v", err))
+		}
+	}
+
+	if config.DryRun {
+		if !config.Quiet {
+			screen.Printf("%s\n", colorize(colorYellow, "🔍 Dry run complete: no requests were sent and no output files were written"))
+		}
+		return
+	}
+
+	writeRunOutputs(runMetrics, config)
+}
 
+// writeRunOutputs saves the metrics file and optional reports, then prints the final summary
+func writeRunOutputs(runMetrics *RunMetrics, config RunConfig) {
 	// Save metrics to file
-	if err := saveMetrics(runMetrics, config); err != nil && config.Verbose {
-		fmt.Printf("%s\n", colorize(colorYellow, fmt.Sprintf("Warning: Failed to save metrics: %v", err)))
+	if err := saveMetrics(runMetrics, config); err != nil && config.Verbose > 0 {
+		printWarning(fmt.Sprintf("Warning: Failed to save metrics: %v", err))
+	}
+
+	if config.HTMLReport != "" {
+		report := buildMetricsReport(runMetrics)
+		if err := WriteHTMLReport(&report, config.HTMLReport); err != nil {
+			printWarning(fmt.Sprintf("Warning: Failed to write HTML report: %v", err))
+		} else if !config.Quiet {
+			screen.Printf("%s\n", colorize(colorGreen, "📄 HTML report saved to: "+config.HTMLReport))
+		}
+	}
+
+	if config.JUnitFile != "" {
+		if err := saveJUnitReport(runMetrics, config.JUnitFile); err != nil {
+			printWarning(fmt.Sprintf("Warning: Failed to write JUnit report: %v", err))
+		} else if !config.Quiet {
+			screen.Printf("%s\n", colorize(colorGreen, "🧪 JUnit report saved to: "+config.JUnitFile))
+		}
 	}
 
 	// Print final summary
@@ -330,25 +697,143 @@ func RunBatch(config RunConfig) {
 	}
 }
 
+// batchRunner holds the state shared by every item and worker during a run
+type batchRunner struct {
+	config         RunConfig
+	collectionAuth *PostmanAuth
+	tracer         *Tracer      // nil when tracing is disabled
+	client         *http.Client // Shared by all workers so connections are pooled
+	proxy          proxyFunc    // Proxy selection used by the client's transport
+	tokens         *tokenCache  // OAuth2 tokens shared by all workers
+	digests        *digestCache // Digest challenges shared by all workers
+	redactor       *redactor    // Masks secrets in everything printed or saved
+	limiter        *rateLimiter // Shared request rate limit (nil = unlimited)
+	variables      map[string]string
+	selector       itemSelector
+	serializer     *rowSerializer   // nil without --serialize-by
+	canary         *canaryPhase     // nil without --canary
+	idempotency    *idempotencyKeys // nil without --idempotency-key
+	budget         chan struct{}    // Request slots shared by parallel items (nil = per item)
+	replay         *replayOptions   // nil unless replaying recorded requests
+	aborted        atomic.Bool      // Set when a canary stops the run
+	outputMu       sync.Mutex       // Serializes multi-line output from workers
+
+	// AWS credentials from the environment or the shared credentials file, read once per run
+	awsCredentials func() (awsCredentials, error)
+}
+
 // processItem recursively processes a Postman item (request or folder)
-func processItem(item PostmanItem, requestList []map[string]string, config RunConfig, runMetrics *RunMetrics, depth int, collectionAuth *PostmanAuth) {
+// itemPath is the slash separated path of folder names leading to the item.
+func (r *batchRunner) processItem(item PostmanItem, itemPath string, records []csvRecord, runMetrics *RunMetrics, depth int) {
+	config := r.config
 	indent := strings.Repeat("  ", depth)
 
+	if !r.selector.hasSelected(item, itemPath) {
+		return
+	}
+
 	// Check if this is a folder
 	if len(item.Item) > 0 {
-		if !config.Quiet {
-			fmt.Printf("%s%s\n", indent, colorize(colorCyan, "📁 Folder: "+item.Name))
+		if !config.Quiet && !r.aborted.Load() {
+			screen.Printf("%s%s\n", indent, colorize(colorCyan, "📁 Folder: "+item.Name))
 		}
 		for _, nestedItem := range item.Item {
-			processItem(nestedItem, requestList, config, runMetrics, depth+1, collectionAuth)
+			r.processItem(nestedItem, joinItemPath(itemPath, nestedItem.Name), records, runMetrics, depth+1)
 		}
 		return
 	}
 
+	// Requests after a canary stopped the run are reported as skipped
+	if r.aborted.Load() {
+		logger.Info("item skipped", "item", itemPath, "records", len(records))
+		now := time.Now()
+		r.outputMu.Lock()
+		defer r.outputMu.Unlock()
+		if !config.Quiet {
+			screen.Printf("%s%s\n", indent, colorize(colorGray, fmt.Sprintf("⏭️  Skipped: %s (%d rows, run aborted by a canary)", item.Name, len(records))))
+		}
+		runMetrics.ItemMetrics = append(runMetrics.ItemMetrics, RequestMetrics{
+			Name:      item.Name,
+			Skipped:   int64(len(records)),
+			StartTime: now,
+			EndTime:   now,
+		})
+		return
+	}
+
 	// This is a request item
+	logger.Info("item started", "item", itemPath, "method", item.Request.Method, "records", len(records))
+	if !config.Quiet {
+		r.outputMu.Lock()
+		screen.Printf("%s%s\n", indent, colorize(colorBold, "🔧 Processing: "+item.Name))
+		screen.Printf("%s   Method: %s | URL: %s\n", indent,
+			colorize(colorPurple, item.Request.Method),
+			colorize(colorGray, r.redactor.URL(item.Request.URL.Raw, r.redactor.referenceSecrets())))
+		workers := fmt.Sprintf("%d", config.Threads)
+		if r.budget != nil {
+			workers += " (shared)"
+		}
+		if r.serializer != nil {
+			workers += " (serialized by " + strings.Join(r.serializer.columns, ", ") + ")"
+		}
+		screen.Printf("%s   Records: %s | Workers: %s\n", indent,
+			colorize(colorYellow, fmt.Sprintf("%d", len(records))),
+			colorize(colorYellow, workers))
+		screen.Println()
+		r.outputMu.Unlock()
+	}
+
+	// The canary rows run first; the rest only if the canary passes. The item
+	// reports both together, and the rows an abort left unsent as skipped.
+	var metrics RequestMetrics
+	if r.canary != nil && len(records) > 0 {
+		canaryRecords, rest := r.canary.split(records)
+		metrics = r.runRecords(item, canaryRecords, item.Name+" (canary)")
+		if !r.canary.decide(metrics, len(rest), indent) {
+			r.aborted.Store(true)
+			metrics.Skipped = int64(len(rest))
+		} else if len(rest) > 0 {
+			metrics.merge(r.runRecords(item, rest, item.Name))
+		}
+	} else {
+		metrics = r.runRecords(item, records, item.Name)
+	}
+
+	// Items running in parallel report as a block once they are done
+	r.outputMu.Lock()
+	defer r.outputMu.Unlock()
+	if r.parallel() && !config.Quiet {
+		screen.Printf("%s%s\n", indent, colorize(colorBold, "🏁 Finished: "+itemPath))
+	}
+
+	// Save failed requests to CSV
+	failedFile := ""
+	if len(metrics.FailedRequests) > 0 {
+		failedFile = saveFailedRequests(metrics.FailedRequests, item.Name, config.OutputDir, r.tracer != nil)
+		if !config.Quiet && failedFile != "" {
+			screen.Printf("%s   %s\n", indent, colorize(colorYellow, fmt.Sprintf("❌ Failed: %d requests saved to %s", len(metrics.FailedRequests), failedFile)))
+			screen.Printf("%s   %s\n", indent, colorize(colorGray, "   (CSV includes error details: status code, message, URL, timestamp)"))
+		}
+	}
+
+	logger.Info("item finished", "item", itemPath, "records", metrics.TotalRequests, "successful", metrics.SuccessCount,
+		"failed", metrics.FailureCount, "skipped", metrics.Skipped, "duration_ms", metrics.EndTime.Sub(metrics.StartTime).Milliseconds(), "failed_file", failedFile)
+
+	// Print summary for this item
+	if !config.Quiet {
+		printRequestSummary(metrics, indent)
+	}
+
+	runMetrics.ItemMetrics = append(runMetrics.ItemMetrics, metrics)
+}
+
+// runRecords sends every record through the item's request on the worker pool
+// and returns the collected metrics. label names the progress bar.
+func (r *batchRunner) runRecords(item PostmanItem, records []csvRecord, label string) RequestMetrics {
+	config := r.config
 	metrics := RequestMetrics{
 		Name:           item.Name,
-		TotalRequests:  int64(len(requestList)),
+		TotalRequests:  int64(len(records)),
 		SuccessCount:   0,
 		FailureCount:   0,
 		MinTime:        time.Hour, // Will be updated
@@ -357,23 +842,23 @@ func processItem(item PostmanItem, requestList []map[string]string, config RunCo
 		FailedRequests: []RequestResult{},
 	}
 
-	if !config.Quiet {
-		fmt.Printf("%s%s\n", indent, colorize(colorBold, "🔧 Processing: "+item.Name))
-		fmt.Printf("%s   Method: %s | URL: %s\n", indent,
-			colorize(colorPurple, item.Request.Method),
-			colorize(colorGray, item.Request.URL.Raw))
-		fmt.Printf("%s   Records: %s | Workers: %s\n", indent,
-			colorize(colorYellow, fmt.Sprintf("%d", len(requestList))),
-			colorize(colorYellow, fmt.Sprintf("%d", config.Threads)))
-		fmt.Println()
-	}
-
 	// Create progress tracker
-	progress := NewProgressTracker(len(requestList), item.Name, config.Quiet)
+	progress := NewProgressTracker(len(records), label, config.Quiet || config.DryRun)
 
-	// Create channels
-	recordsChan := make(chan map[string]string, len(requestList))
-	resultsChan := make(chan RequestResult, len(requestList))
+	// Distribute work: workers share one queue, or with --serialize-by each
+	// worker has its own queue holding every row of its keys
+	var queues []chan csvRecord
+	if r.serializer != nil {
+		queues = r.serializer.queues(records, config.Threads)
+	} else {
+		recordsChan := make(chan csvRecord, len(records))
+		for _, record := range records {
+			recordsChan <- record
+		}
+		close(recordsChan)
+		queues = []chan csvRecord{recordsChan}
+	}
+	resultsChan := make(chan RequestResult, len(records))
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex // Protect metrics updates
@@ -381,14 +866,8 @@ func processItem(item PostmanItem, requestList []map[string]string, config RunCo
 	// Spawn workers
 	for i := 1; i <= config.Threads; i++ {
 		wg.Add(1)
-		go worker(i, item, recordsChan, resultsChan, &wg, config, collectionAuth)
-	}
-
-	// Distribute work
-	for _, record := range requestList {
-		recordsChan <- record
+		go r.worker(i, item, queues[(i-1)%len(queues)], resultsChan, progress, &wg)
 	}
-	close(recordsChan)
 
 	// Collect results in background
 	go func() {
@@ -399,50 +878,22 @@ func processItem(item PostmanItem, requestList []map[string]string, config RunCo
 	// Process results
 	for result := range resultsChan {
 		mu.Lock()
-		if result.Success {
-			metrics.SuccessCount++
-		} else {
-			metrics.FailureCount++
-			metrics.FailedRequests = append(metrics.FailedRequests, result)
-		}
-
-		// Update timing metrics
-		if result.ResponseTime < metrics.MinTime {
-			metrics.MinTime = result.ResponseTime
-		}
-		if result.ResponseTime > metrics.MaxTime {
-			metrics.MaxTime = result.ResponseTime
-		}
-		metrics.TotalTime += result.ResponseTime
+		metrics.recordResult(result)
 		mu.Unlock()
 
-		progress.Update(result.Success)
+		progress.Update(result)
 	}
 
 	progress.Finish()
 	metrics.EndTime = time.Now()
-
-	// Save failed requests to CSV
-	if len(metrics.FailedRequests) > 0 {
-		failedFile := saveFailedRequests(metrics.FailedRequests, item.Name)
-		if !config.Quiet && failedFile != "" {
-			fmt.Printf("%s   %s\n", indent, colorize(colorYellow, fmt.Sprintf("❌ Failed: %d requests saved to %s", len(metrics.FailedRequests), failedFile)))
-			fmt.Printf("%s   %s\n", indent, colorize(colorGray, "   (CSV includes error details: status code, message, URL, timestamp)"))
-		}
-	}
-
-	// Print summary for this item
-	if !config.Quiet {
-		printRequestSummary(metrics, indent)
-	}
-
-	runMetrics.ItemMetrics = append(runMetrics.ItemMetrics, metrics)
+	return metrics
 }
 
 // resolveAuth determines which auth to use based on hierarchy:
 // 1. CLI override (--bearer-token flag)
 // 2. Request-level auth
 // 3. Collection-level auth
+// A configured auth override has already replaced 2 and 3 (see overrideAuth).
 func resolveAuth(collectionAuth *PostmanAuth, requestAuth *PostmanAuth, cliToken string) *PostmanAuth {
 	// CLI override takes precedence
 	if cliToken != "" {
@@ -463,12 +914,67 @@ func resolveAuth(collectionAuth *PostmanAuth, requestAuth *PostmanAuth, cliToken
 	return collectionAuth
 }
 
+// overrideAuth makes auth the collection auth and removes every request-level auth
+func overrideAuth(collection *PostmanCollection, auth *PostmanAuth) {
+	collection.Auth = auth
+	var clear func(items []PostmanItem)
+	clear = func(items []PostmanItem) {
+		for i := range items {
+			items[i].Request.Auth = nil
+			clear(items[i].Item)
+		}
+	}
+	clear(collection.Item)
+}
+
+// supportedAuthTypes lists the Postman auth types applyAuth and signRequest implement
+var supportedAuthTypes = map[string]bool{
+	"noauth": true,
+	"bearer": true,
+	"apikey": true,
+	"basic":  true,
+	"oauth2": true,
+	"awsv4":  true,
+	"digest": true,
+	"hawk":   true,
+}
+
+// validateAuthTypes returns an error naming the first collection or request
+// auth whose type is not supported
+func validateAuthTypes(collection PostmanCollection) error {
+	check := func(auth *PostmanAuth, where string) error {
+		if auth == nil || supportedAuthTypes[auth.Type] {
+			return nil
+		}
+		return fmt.Errorf("unsupported auth type %q on %s (supported: noauth, bearer, apikey, basic, oauth2, awsv4, digest, hawk)", auth.Type, where)
+	}
+
+	if err := check(collection.Auth, "the collection"); err != nil {
+		return err
+	}
+	var walk func(items []PostmanItem) error
+	walk = func(items []PostmanItem) error {
+		for _, item := range items {
+			if err := check(item.Request.Auth, fmt.Sprintf("request %q", item.Name)); err != nil {
+				return err
+			}
+			if err := walk(item.Item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(collection.Item)
+}
+
 // applyAuth applies authentication to an HTTP request
 // Supports both object format {"token": "abc"} and array format [{"key": "token", "value": "abc"}]
-// Handles bearer tokens, API keys, and basic auth with template variable replacement
-func applyAuth(req *http.Request, auth *PostmanAuth, csvData map[string]string) {
+// Handles bearer tokens, API keys, basic auth and OAuth2 with template variable replacement
+// AWS SigV4, Hawk and Digest are applied separately by signRequest after all headers are set
+// An error means credentials could not be obtained and the request must not be sent
+func applyAuth(req *http.Request, auth *PostmanAuth, csvData map[string]string, tokens *tokenCache) error {
 	if auth == nil {
-		return
+		return nil
 	}
 
 	switch auth.Type {
@@ -481,7 +987,10 @@ func applyAuth(req *http.Request, auth *PostmanAuth, csvData map[string]string)
 		}
 
 	case "apikey":
-		keyName, keyValue := extractAPIKey(auth.APIKeyRaw)
+		keyName, keyValue, in := extractAPIKey(auth.APIKeyRaw)
+		if in == "query" {
+			break // Added to the URL by apiKeyQueryParam
+		}
 		if keyName != "" && keyValue != "" {
 			// Replace template variables in both key and value
 			keyName = replaceTemplateVariables(keyName, csvData)
@@ -497,7 +1006,62 @@ func applyAuth(req *http.Request, auth *PostmanAuth, csvData map[string]string)
 			password = replaceTemplateVariables(password, csvData)
 			req.SetBasicAuth(username, password)
 		}
+
+	case "oauth2":
+		params := extractOAuth2(auth.OAuth2Raw, csvData)
+		token, err := tokens.Token(params)
+		if err != nil {
+			return err
+		}
+		setOAuth2Token(req, params, token)
+
+	case "awsv4", "hawk", "digest":
+		// Signed by signRequest once headers and body are final
+	}
+
+	return nil
+}
+
+// setOAuth2Token adds an access token to the Authorization header
+func setOAuth2Token(req *http.Request, params oauth2Params, token string) {
+	prefix := params.HeaderPrefix
+	if prefix == "" {
+		prefix = "Bearer"
+	}
+	req.Header.Set("Authorization", prefix+" "+token)
+}
+
+// extractAuthParams flattens auth parameters from either object format
+// {"key": "value"} or array format [{"key": "key", "value": "value"}]
+func extractAuthParams(raw json.RawMessage) map[string]string {
+	params := make(map[string]string)
+	if len(raw) == 0 {
+		return params
+	}
+
+	var objFormat map[string]interface{}
+	if err := json.Unmarshal(raw, &objFormat); err == nil {
+		for key, value := range objFormat {
+			if value != nil {
+				params[key] = fmt.Sprintf("%v", value)
+			}
+		}
+		return params
+	}
+
+	// Values are not always strings: Postman exports flags such as includePayloadHash as booleans
+	var arrayFormat []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &arrayFormat); err == nil {
+		for _, kv := range arrayFormat {
+			if kv.Value != nil {
+				params[kv.Key] = fmt.Sprintf("%v", kv.Value)
+			}
+		}
 	}
+	return params
 }
 
 // extractBearerToken extracts bearer token from either object or array format
@@ -528,36 +1092,84 @@ func extractBearerToken(raw json.RawMessage) string {
 }
 
 // extractAPIKey extracts API key from either object or array format
-func extractAPIKey(raw json.RawMessage) (string, string) {
+// The third value is where to send the key: "header" (default) or "query"
+func extractAPIKey(raw json.RawMessage) (string, string, string) {
 	if len(raw) == 0 {
-		return "", ""
+		return "", "", ""
 	}
 
-	// Try object format first: {"key": "X-API-Key", "value": "abc"}
+	// Try object format first: {"key": "X-API-Key", "value": "abc", "in": "header"}
 	var objFormat struct {
 		Key   string `json:"key"`
 		Value string `json:"value"`
+		In    string `json:"in"`
 	}
 	if err := json.Unmarshal(raw, &objFormat); err == nil && objFormat.Key != "" {
-		return objFormat.Key, objFormat.Value
+		return objFormat.Key, objFormat.Value, apiKeyLocation(objFormat.In)
 	}
 
-	// Try array format: [{"key": "key", "value": "X-API-Key"}, {"key": "value", "value": "abc"}]
+	// Try array format: [{"key": "key", "value": "X-API-Key"}, {"key": "value", "value": "abc"}, {"key": "in", "value": "query"}]
 	var arrayFormat []PostmanKV
 	if err := json.Unmarshal(raw, &arrayFormat); err == nil {
 		keyName := ""
 		keyValue := ""
+		in := ""
 		for _, kv := range arrayFormat {
-			if kv.Key == "key" {
+			switch kv.Key {
+			case "key":
 				keyName = kv.Value
-			} else if kv.Key == "value" {
+			case "value":
 				keyValue = kv.Value
+			case "in":
+				in = kv.Value
 			}
 		}
-		return keyName, keyValue
+		return keyName, keyValue, apiKeyLocation(in)
 	}
 
-	return "", ""
+	return "", "", ""
+}
+
+// apiKeyLocation normalizes the apikey "in" field
+func apiKeyLocation(in string) string {
+	if strings.EqualFold(strings.TrimSpace(in), "query") {
+		return "query"
+	}
+	return "header"
+}
+
+// apiKeyQueryParam returns the query parameter for apikey auth sent in the query string.
+// The name is rendered here; the value is left for BuildURLWithQueryParams to render.
+func apiKeyQueryParam(auth *PostmanAuth, csvData map[string]string) (string, string, bool) {
+	if auth == nil || auth.Type != "apikey" {
+		return "", "", false
+	}
+	keyName, keyValue, in := extractAPIKey(auth.APIKeyRaw)
+	if in != "query" || keyName == "" || keyValue == "" {
+		return "", "", false
+	}
+	return replaceTemplateVariables(keyName, csvData), keyValue, true
+}
+
+// maskQueryParam replaces the value of a query parameter so the URL can be logged
+func maskQueryParam(rawURL, name string) string {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL
+	}
+	fragment := ""
+	if i := strings.IndexByte(query, '#'); i >= 0 {
+		query, fragment = query[:i], query[i:]
+	}
+
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil && unescaped == name {
+			pairs[i] = key + "=****"
+		}
+	}
+	return base + "?" + strings.Join(pairs, "&") + fragment
 }
 
 // extractBasicAuth extracts basic auth credentials from either object or array format
@@ -594,129 +1206,425 @@ func extractBasicAuth(raw json.RawMessage) (string, string) {
 }
 
 // worker processes CSV records and executes HTTP requests
-func worker(id int, item PostmanItem, records chan map[string]string, results chan RequestResult, wg *sync.WaitGroup, config RunConfig, collectionAuth *PostmanAuth) {
+func (r *batchRunner) worker(id int, item PostmanItem, records chan csvRecord, results chan RequestResult, progress *ProgressTracker, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	for csvRow := range records {
-		startTime := time.Now()
+	for record := range records {
+		if r.budget != nil {
+			r.budget <- struct{}{}
+		}
+		progress.Started()
+		result, span := r.executeWithRetries(item, record)
+		if r.budget != nil {
+			<-r.budget
+		}
+		secrets := r.rowSecrets(item, record)
+		result = r.redactor.Result(result, secrets)
+		if r.config.RecordRequests && result.detail != nil && result.detail.requestHeader != nil {
+			result.Request = r.redactor.Request(item.Name, result.detail, result.Method, secrets)
+		}
+		result.detail = nil // Responses can be large; failed results are kept until the item ends
+		if !r.config.DryRun {
+			logResult(item, result)
+		}
 
-		csvData := make(map[string]interface{})
-		for column, value := range csvRow {
-			csvData[column] = value
+		if span != nil {
+			result.TraceID = span.TraceIDString()
+			if result.URL != "" {
+				span.SetAttribute("url.full", result.URL)
+			}
+			if result.StatusCode != 0 {
+				span.SetAttribute("http.response.status_code", result.StatusCode)
+			}
+			if result.IdempotencyKey != "" {
+				span.SetAttribute("backfill.idempotency_key", result.IdempotencyKey)
+			}
+			if !result.Success {
+				span.StatusError = true
+				span.StatusMessage = result.Error
+			}
+			r.tracer.EndSpan(span)
 		}
 
-		recordInfo := getRecordInfo(csvRow)
+		results <- result
+	}
+}
 
-		result := RequestResult{
-			Timestamp:   startTime,
-			RequestName: item.Name,
-			Method:      item.Request.Method,
-			CSVData:     csvRow,
-			RecordInfo:  recordInfo,
-		}
+// startSpan starts the span of a row, or returns nil without tracing
+func (r *batchRunner) startSpan(item PostmanItem, record csvRecord) *Span {
+	if r.tracer == nil {
+		return nil
+	}
+	span := r.tracer.StartSpan(item.Request.Method + " " + item.Name)
+	span.SetAttribute("backfill.row_index", record.Index)
+	span.SetAttribute("backfill.item_name", item.Name)
+	span.SetAttribute("url.template", r.redactor.URL(item.Request.URL.Raw, r.redactor.referenceSecrets()))
+	span.SetAttribute("http.request.method", item.Request.Method)
+	return span
+}
+
+// executeRequest renders the item's request for one CSV record and sends it
+func (r *batchRunner) executeRequest(item PostmanItem, record csvRecord, span *Span) RequestResult {
+	if r.replay != nil {
+		return r.replayRequest(item, record, span)
+	}
+	startTime := time.Now()
+	csvRow := withVariables(record.Data, r.variables)
 
-		// Replace URL variables (path variables and query parameters)
-		finalURL, err := BuildURLWithQueryParams(item.Request.URL, csvRow)
+	csvData := make(map[string]interface{})
+	for column, value := range csvRow {
+		csvData[column] = value
+	}
+
+	result := RequestResult{
+		Timestamp:   startTime,
+		RequestName: item.Name,
+		Method:      item.Request.Method,
+		CSVData:     record.Data,
+		RecordInfo:  getRecordInfo(record.Data),
+		RowIndex:    record.Index,
+		detail:      newRequestDetail(r.config),
+	}
+
+	auth := resolveAuth(r.collectionAuth, item.Request.Auth, r.config.BearerToken)
+
+	// API keys sent in the query string join the structured query parameters
+	requestURL := item.Request.URL
+	queryKeyName, queryKeyValue, keyInQuery := apiKeyQueryParam(auth, csvRow)
+	if keyInQuery {
+		requestURL.Query = append(append([]QueryParam{}, requestURL.Query...), QueryParam{Key: queryKeyName, Value: queryKeyValue})
+	}
+
+	// Replace URL variables (path variables and query parameters)
+	finalURL, err := BuildURLWithQueryParams(requestURL, csvRow)
+	if err != nil {
+		result.Error = fmt.Sprintf("Error processing URL: %v", err)
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	// Only the masked URL is logged, traced or saved
+	result.URL = finalURL
+	if keyInQuery {
+		result.URL = maskQueryParam(finalURL, queryKeyName)
+	}
+
+	// Replace body variables
+	var modifiedBody string
+	if item.Request.Body.Raw != "" {
+		modifiedBody, err = ReplaceJSONValues(item.Request.Body.Raw, csvData)
 		if err != nil {
-			result.Success = false
-			result.Error = fmt.Sprintf("Error processing URL: %v", err)
-			result.ResponseTime = time.Since(startTime)
-			results <- result
+			modifiedBody = replaceTemplateVariables(item.Request.Body.Raw, csvRow)
+		}
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest(item.Request.Method, finalURL, bytes.NewBufferString(modifiedBody))
+	if err != nil {
+		result.Error = fmt.Sprintf("Error creating request: %v", err)
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	// Apply authentication
+	if err := applyAuth(req, auth, csvRow, r.tokens); err != nil {
+		result.AuthError = true
+		result.Error = fmt.Sprintf("Auth failed: %v", err)
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	// Set headers (after auth so explicit headers can override auth headers if needed)
+	for _, header := range item.Request.Header {
+		if header.Key == "" || header.Value == "" {
 			continue
 		}
-		result.URL = finalURL
+		headerValue := replaceTemplateVariables(header.Value, csvRow)
+		req.Header.Set(header.Key, headerValue)
+	}
+
+	// Default Content-Type
+	if modifiedBody != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Retries and re-runs of a row send the same key, unless the collection sets one
+	if r.idempotency != nil {
+		if req.Header.Get(r.idempotency.header) == "" {
+			req.Header.Set(r.idempotency.header, r.idempotency.Key(item.Name, record))
+		}
+		result.IdempotencyKey = req.Header.Get(r.idempotency.header)
+	}
 
-		// Replace body variables
-		var modifiedBody string
-		if item.Request.Body.Raw != "" {
-			modifiedBody, err = ReplaceJSONValues(item.Request.Body.Raw, csvData)
-			if err != nil {
-				modifiedBody = replaceTemplateVariables(item.Request.Body.Raw, csvRow)
+	// Propagate trace context so server-side spans join this request's trace
+	if span != nil {
+		req.Header.Set("traceparent", span.Traceparent())
+	}
+
+	// Signatures cover the headers and body, so signing must be the last change to the request
+	if err := r.signRequest(req, auth, csvRow, []byte(modifiedBody)); err != nil {
+		result.AuthError = true
+		result.Error = fmt.Sprintf("Auth failed: %v", err)
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	return r.send(item, record, req, modifiedBody, auth, csvRow, result)
+}
+
+// send sends a rendered request and completes its result. auth and csvData
+// answer a 401 for OAuth2 and Digest auth; both are nil for replayed requests.
+func (r *batchRunner) send(item PostmanItem, record csvRecord, req *http.Request, body string, auth *PostmanAuth, csvData map[string]string, result RequestResult) RequestResult {
+	startTime := result.Timestamp
+	if detail := result.detail; detail != nil {
+		detail.requestURL = req.URL.String()
+		detail.requestHeader = req.Header.Clone()
+		detail.requestBody = body
+	}
+
+	// Record connection reuse and TLS handshake time, and for --verbose how the connection was made
+	var tlsStart, dnsStart, connStart time.Time
+	connTrace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.ConnReused = info.Reused
+			if detail := result.detail; detail != nil && info.Conn != nil {
+				detail.remoteAddr = info.Conn.RemoteAddr().String()
+				detail.idleTime = info.IdleTime
+			}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if detail := result.detail; detail != nil {
+				detail.dnsTime = time.Since(dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) {
+			connStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			if detail := result.detail; detail != nil {
+				detail.connTime = time.Since(connStart)
 			}
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			result.TLSHandshake = time.Since(tlsStart)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), connTrace))
+
+	if r.config.DryRun {
+		r.printDryRun(item, record, req, result.URL, body)
+		result.Success = true
+		result.Message = "dry run"
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+
+	// Execute request
+	resp, err := r.client.Do(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && auth != nil {
+		switch auth.Type {
+		case "oauth2":
+			// The token may have been revoked or expired early; retry once with a fresh one
+			resp, err = r.retryWithFreshToken(req, resp, auth, csvData)
+		case "digest":
+			// Answer the server's challenge (or a stale nonce) and retry once
+			resp, err = r.retryWithDigest(req, resp, auth, csvData, []byte(body))
 		}
+	}
+	var tokenErr *tokenError
+	if errors.As(err, &tokenErr) {
+		result.AuthError = true
+		result.Error = fmt.Sprintf("Auth failed: %v", err)
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
+	if err != nil {
+		result.Error = strings.ReplaceAll(fmt.Sprintf("Request failed: %v", err), req.URL.String(), result.URL)
+		result.NetworkError = true
+		result.ResponseTime = time.Since(startTime)
+		return result
+	}
 
-		// Create HTTP request
-		req, err := http.NewRequest(item.Request.Method, finalURL, bytes.NewBufferString(modifiedBody))
-		if err != nil {
-			result.Success = false
-			result.Error = fmt.Sprintf("Error creating request: %v", err)
-			result.ResponseTime = time.Since(startTime)
-			results <- result
-			continue
+	// Read response
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	result.ResponseTime = time.Since(startTime)
+	result.StatusCode = resp.StatusCode
+	result.Protocol = resp.Proto
+	result.RetryAfter = parseRetryAfter(resp.Header)
+	result.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+
+	if detail := result.detail; detail != nil {
+		detail.responseStatus = resp.Proto + " " + resp.Status
+		detail.responseHeader = resp.Header
+		detail.tlsState = resp.TLS
+		if r.config.Verbose >= VerboseBodies {
+			detail.responseBody = string(respBody)
 		}
+	}
 
-		// Resolve and apply authentication
-		auth := resolveAuth(collectionAuth, item.Request.Auth, config.BearerToken)
-		applyAuth(req, auth, csvRow)
+	if err != nil {
+		result.Error = fmt.Sprintf("Error reading response: %v", err)
+		result.Success = false
+	} else {
+		message := string(respBody)
+		if len(message) > 100 {
+			message = message[:100] + "..."
+		}
+		result.Message = message
 
-		// Set headers (after auth so explicit headers can override auth headers if needed)
-		for _, header := range item.Request.Header {
-			if header.Key == "" || header.Value == "" {
-				continue
-			}
-			headerValue := replaceTemplateVariables(header.Value, csvRow)
-			req.Header.Set(header.Key, headerValue)
+		if !result.Success {
+			result.Error = fmt.Sprintf("HTTP %d: %s", resp.StatusCode, message)
 		}
+	}
+
+	return result
+}
+
+// retryWithFreshToken discards a 401 response, drops the rejected OAuth2 token
+// from the cache and resends the request with a newly fetched token
+func (r *batchRunner) retryWithFreshToken(req *http.Request, resp *http.Response, auth *PostmanAuth, csvData map[string]string) (*http.Response, error) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	params := extractOAuth2(auth.OAuth2Raw, csvData)
+	prefix := params.HeaderPrefix
+	if prefix == "" {
+		prefix = "Bearer"
+	}
+	r.tokens.Invalidate(params, strings.TrimPrefix(req.Header.Get("Authorization"), prefix+" "))
+
+	token, err := r.tokens.Token(params)
+	if err != nil {
+		return nil, &tokenError{fmt.Errorf("refreshing OAuth2 token after 401: %v", err)}
+	}
 
-		// Default Content-Type
-		if modifiedBody != "" && req.Header.Get("Content-Type") == "" {
-			req.Header.Set("Content-Type", "application/json")
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
 		}
+	}
+	setOAuth2Token(retry, params, token)
+	return r.client.Do(retry)
+}
+
+// signRequest applies auth schemes that sign the final request.
+// Digest only signs here when a challenge for the host is already cached;
+// otherwise the first request goes out unauthenticated and retryWithDigest answers the 401.
+func (r *batchRunner) signRequest(req *http.Request, auth *PostmanAuth, csvData map[string]string, body []byte) error {
+	if auth == nil {
+		return nil
+	}
 
-		// Execute request
-		client := &http.Client{
-			Timeout: 30 * time.Second,
+	switch auth.Type {
+	case "awsv4":
+		params, err := extractAWSv4(auth.AWSv4Raw, csvData, r.awsCredentials)
+		if err != nil {
+			return err
 		}
+		signAWSv4(req, body, params, time.Now())
 
-		resp, err := client.Do(req)
+	case "hawk":
+		params, err := extractHawk(auth.HawkRaw, csvData)
 		if err != nil {
-			result.Success = false
-			result.Error = fmt.Sprintf("Request failed: %v", err)
-			result.ResponseTime = time.Since(startTime)
-			results <- result
-			continue
+			return err
 		}
+		signHawk(req, body, params, time.Now())
 
-		// Read response
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+	case "digest":
+		username, password := extractDigestAuth(auth.DigestRaw, csvData)
+		if username == "" {
+			return fmt.Errorf("digest auth requires a username")
+		}
+		r.digests.Authorize(req, username, password, body)
+	}
+	return nil
+}
 
-		result.ResponseTime = time.Since(startTime)
-		result.StatusCode = resp.StatusCode
-		result.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+// retryWithDigest answers a Digest challenge from a 401 response and resends the request.
+// Responses without a Digest challenge are returned unchanged.
+func (r *batchRunner) retryWithDigest(req *http.Request, resp *http.Response, auth *PostmanAuth, csvData map[string]string, body []byte) (*http.Response, error) {
+	challenge, ok := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
 
-		if err != nil {
-			result.Error = fmt.Sprintf("Error reading response: %v", err)
-			result.Success = false
-		} else {
-			message := string(respBody)
-			if len(message) > 100 {
-				message = message[:100] + "..."
-			}
-			result.Message = message
+	username, password := extractDigestAuth(auth.DigestRaw, csvData)
+	r.digests.Store(req, username, challenge)
 
-			if !result.Success {
-				result.Error = fmt.Sprintf("HTTP %d: %s", resp.StatusCode, message)
-			}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		var err error
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
 		}
+	}
+	r.digests.Authorize(retry, username, password, body)
+	return r.client.Do(retry)
+}
 
-		results <- result
+// rowSecrets returns the secret values of one row of an item
+func (r *batchRunner) rowSecrets(item PostmanItem, record csvRecord) []string {
+	auth := resolveAuth(r.collectionAuth, item.Request.Auth, r.config.BearerToken)
+	return r.redactor.rowSecrets(auth, withVariables(record.Data, r.variables))
+}
+
+// printDryRun prints a fully rendered request instead of sending it
+// displayURL is the request URL with secrets masked.
+func (r *batchRunner) printDryRun(item PostmanItem, record csvRecord, req *http.Request, displayURL, body string) {
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	secrets := r.rowSecrets(item, record)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", colorize(colorGray, fmt.Sprintf("--- %s | row %d", item.Name, record.Index)))
+	fmt.Fprintf(&b, "%s %s\n", colorize(colorPurple, req.Method), r.redactor.URL(displayURL, secrets))
+	fmt.Fprintf(&b, "%s\n", colorize(colorGray, "Proxy: "+describeProxy(r.proxy, req)))
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, r.redactor.Header(name, value, secrets))
+		}
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.redactor.String(body, secrets))
+	}
+
+	r.outputMu.Lock()
+	screen.Println(b.String())
+	r.outputMu.Unlock()
 }
 
 // saveFailedRequests saves failed requests to a CSV file for retry
 // The CSV includes original data columns PLUS error detail columns at the end
 // This allows both: (1) easy retry by re-uploading, (2) viewing error details
 // Error columns are ignored during retry since they don't match template variables
-func saveFailedRequests(failedRequests []RequestResult, requestName string) string {
+func saveFailedRequests(failedRequests []RequestResult, requestName, dir string, includeTraceID bool) string {
 	if len(failedRequests) == 0 {
 		return ""
 	}
 
+	// Workers finish out of order; save failures in CSV order
+	failedRequests = append([]RequestResult(nil), failedRequests...)
+	sort.Slice(failedRequests, func(i, j int) bool { return failedRequests[i].RowIndex < failedRequests[j].RowIndex })
+
 	// Generate filename with timestamp
 	timestamp := time.Now().Format("20060102_150405")
 	safeName := strings.ReplaceAll(requestName, " ", "_")
-	filename := fmt.Sprintf("failed_requests_%s_%s.csv", safeName, timestamp)
+	filename := filepath.Join(dir, fmt.Sprintf("failed_requests_%s_%s.csv", safeName, timestamp))
 
 	file, err := os.Create(filename)
 	if err != nil {
@@ -727,16 +1635,25 @@ func saveFailedRequests(failedRequests []RequestResult, requestName string) stri
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	// Collect all unique CSV column names from original data
+	// Collect all unique CSV column names from original data. The idempotency key
+	// and error columns of a re-run failed request CSV are written again below.
 	headers := []string{}
-	headerMap := make(map[string]bool)
+	headerMap := map[string]bool{idempotencyKeyColumn: true, idempotencyItemColumn: true}
+	withKeys := false
+	withRequests := false
 	for _, fr := range failedRequests {
 		for key := range fr.CSVData {
-			if !headerMap[key] {
+			if !headerMap[key] && !strings.HasPrefix(key, "_error_") && !strings.HasPrefix(key, "_request_") {
 				headers = append(headers, key)
 				headerMap[key] = true
 			}
 		}
+		key, _ := idempotencyKeyOf(fr)
+		withKeys = withKeys || key != ""
+		withRequests = withRequests || recordedColumnsOf(fr) != nil
+	}
+	if withKeys {
+		headers = append(headers, idempotencyKeyColumn, idempotencyItemColumn)
 	}
 
 	// Add error detail columns at the end (these will be ignored on retry)
@@ -748,7 +1665,14 @@ func saveFailedRequests(failedRequests []RequestResult, requestName string) stri
 		"_error_timestamp",
 		"_error_response_time_ms",
 	}
+	if includeTraceID {
+		errorColumns = append(errorColumns, "_error_trace_id")
+	}
 	allHeaders := append(headers, errorColumns...)
+	// The recorded request follows, for replay
+	if withRequests {
+		allHeaders = append(allHeaders, requestColumns...)
+	}
 
 	// Write header row
 	writer.Write(allHeaders)
@@ -761,6 +1685,9 @@ func saveFailedRequests(failedRequests []RequestResult, requestName string) stri
 		for i, header := range headers {
 			row[i] = fr.CSVData[header]
 		}
+		if withKeys {
+			row[len(headers)-2], row[len(headers)-1] = idempotencyKeyOf(fr)
+		}
 
 		// Fill error detail columns
 		offset := len(headers)
@@ -770,6 +1697,12 @@ func saveFailedRequests(failedRequests []RequestResult, requestName string) stri
 		row[offset+3] = fr.Method
 		row[offset+4] = fr.Timestamp.Format(time.RFC3339)
 		row[offset+5] = fmt.Sprintf("%d", fr.ResponseTime.Milliseconds())
+		if includeTraceID {
+			row[offset+6] = fr.TraceID
+		}
+		if withRequests {
+			copy(row[len(headers)+len(errorColumns):], recordedColumnsOf(fr))
+		}
 
 		writer.Write(row)
 	}
@@ -797,63 +1730,11 @@ func saveMetrics(runMetrics *RunMetrics, config RunConfig) error {
 	filename := config.MetricsFile
 	if filename == "" {
 		timestamp := time.Now().Format("20060102_150405")
-		filename = fmt.Sprintf("metrics_%s.json", timestamp)
+		filename = filepath.Join(config.OutputDir, fmt.Sprintf("metrics_%s.json", timestamp))
 	}
 
-	// Calculate summary statistics
-	totalSuccess := int64(0)
-	totalFailure := int64(0)
-	totalRequests := int64(0)
-	for _, item := range runMetrics.ItemMetrics {
-		totalSuccess += item.SuccessCount
-		totalFailure += item.FailureCount
-		totalRequests += item.TotalRequests
-	}
-
-	// Create output structure
-	output := map[string]interface{}{
-		"collection_name": runMetrics.CollectionName,
-		"csv_file":        runMetrics.CSVFile,
-		"start_time":      runMetrics.StartTime.Format(time.RFC3339),
-		"end_time":        runMetrics.EndTime.Format(time.RFC3339),
-		"duration_seconds": runMetrics.EndTime.Sub(runMetrics.StartTime).Seconds(),
-		"total_records":   runMetrics.TotalRecords,
-		"summary": map[string]interface{}{
-			"total_requests":   totalRequests,
-			"successful":       totalSuccess,
-			"failed":           totalFailure,
-			"success_rate_pct": float64(totalSuccess) / float64(totalRequests) * 100,
-		},
-		"items": []map[string]interface{}{},
-	}
-
-	// Add per-item metrics
-	items := []map[string]interface{}{}
-	for _, item := range runMetrics.ItemMetrics {
-		avgTime := time.Duration(0)
-		if item.SuccessCount+item.FailureCount > 0 {
-			avgTime = item.TotalTime / time.Duration(item.SuccessCount+item.FailureCount)
-		}
-
-		itemData := map[string]interface{}{
-			"name":            item.Name,
-			"total_requests":  item.TotalRequests,
-			"successful":      item.SuccessCount,
-			"failed":          item.FailureCount,
-			"success_rate_pct": float64(item.SuccessCount) / float64(item.TotalRequests) * 100,
-			"timing": map[string]interface{}{
-				"avg_ms": avgTime.Milliseconds(),
-				"min_ms": item.MinTime.Milliseconds(),
-				"max_ms": item.MaxTime.Milliseconds(),
-			},
-			"duration_seconds": item.EndTime.Sub(item.StartTime).Seconds(),
-		}
-		items = append(items, itemData)
-	}
-	output["items"] = items
-
 	// Write to file
-	data, err := json.MarshalIndent(output, "", "  ")
+	data, err := json.MarshalIndent(buildMetricsReport(runMetrics), "", "  ")
 	if err != nil {
 		return err
 	}
@@ -862,8 +1743,9 @@ func saveMetrics(runMetrics *RunMetrics, config RunConfig) error {
 		return err
 	}
 
+	logger.Info("metrics saved", "file", filename)
 	if !config.Quiet {
-		fmt.Printf("\n%s\n", colorize(colorGreen, "💾 Metrics saved to: "+filename))
+		screen.Printf("\n%s\n", colorize(colorGreen, "💾 Metrics saved to: "+filename))
 	}
 
 	return nil
@@ -871,23 +1753,33 @@ func saveMetrics(runMetrics *RunMetrics, config RunConfig) error {
 
 // printRequestSummary prints summary for a single request
 func printRequestSummary(metrics RequestMetrics, indent string) {
-	fmt.Println()
-	fmt.Printf("%s%s\n", indent, colorize(colorBold, "📊 Summary:"))
+	screen.Println()
+	screen.Printf("%s%s\n", indent, colorize(colorBold, "📊 Summary:"))
 
-	successRate := float64(metrics.SuccessCount) / float64(metrics.TotalRequests) * 100
+	successRate := percentOf(metrics.SuccessCount, metrics.TotalRequests)
 	avgTime := time.Duration(0)
 	if metrics.SuccessCount+metrics.FailureCount > 0 {
 		avgTime = metrics.TotalTime / time.Duration(metrics.SuccessCount+metrics.FailureCount)
 	}
 
-	fmt.Printf("%s   Total:        %s\n", indent, colorize(colorCyan, fmt.Sprintf("%d", metrics.TotalRequests)))
-	fmt.Printf("%s   Successful:   %s (%.1f%%)\n", indent, colorize(colorGreen, fmt.Sprintf("%d", metrics.SuccessCount)), successRate)
-	fmt.Printf("%s   Failed:       %s (%.1f%%)\n", indent, colorize(colorRed, fmt.Sprintf("%d", metrics.FailureCount)), 100-successRate)
-	fmt.Printf("%s   Avg Time:     %dms\n", indent, avgTime.Milliseconds())
-	fmt.Printf("%s   Min Time:     %dms\n", indent, metrics.MinTime.Milliseconds())
-	fmt.Printf("%s   Max Time:     %dms\n", indent, metrics.MaxTime.Milliseconds())
-	fmt.Printf("%s   Duration:     %s\n", indent, formatDuration(metrics.EndTime.Sub(metrics.StartTime)))
-	fmt.Println()
+	screen.Printf("%s   Total:        %s\n", indent, colorize(colorCyan, fmt.Sprintf("%d", metrics.TotalRequests)))
+	screen.Printf("%s   Successful:   %s (%.1f%%)\n", indent, colorize(colorGreen, fmt.Sprintf("%d", metrics.SuccessCount)), successRate)
+	screen.Printf("%s   Failed:       %s (%.1f%%)\n", indent, colorize(colorRed, fmt.Sprintf("%d", metrics.FailureCount)), percentOf(metrics.FailureCount, metrics.TotalRequests))
+	if metrics.Skipped > 0 {
+		screen.Printf("%s   Skipped:      %s (not sent, run aborted)\n", indent, colorize(colorRed, fmt.Sprintf("%d", metrics.Skipped)))
+	}
+	if metrics.AuthFailures > 0 {
+		screen.Printf("%s   Auth Failed:  %s (not sent)\n", indent, colorize(colorRed, fmt.Sprintf("%d", metrics.AuthFailures)))
+	}
+	if metrics.Retries > 0 {
+		screen.Printf("%s   Retries:      %s\n", indent, colorize(colorYellow, fmt.Sprintf("%d", metrics.Retries)))
+	}
+	screen.Printf("%s   Avg Time:     %dms\n", indent, avgTime.Milliseconds())
+	screen.Printf("%s   Min Time:     %dms\n", indent, metrics.MinTime.Milliseconds())
+	screen.Printf("%s   Max Time:     %dms\n", indent, metrics.MaxTime.Milliseconds())
+	screen.Printf("%s   Duration:     %s\n", indent, formatDuration(metrics.EndTime.Sub(metrics.StartTime)))
+	screen.Printf("%s   Connections:  %d new, %d reused\n", indent, metrics.NewConns, metrics.ReusedConns)
+	screen.Println()
 }
 
 // printFinalSummary prints overall execution summary
@@ -895,26 +1787,37 @@ func printFinalSummary(runMetrics *RunMetrics) {
 	totalSuccess := int64(0)
 	totalFailure := int64(0)
 	totalRequests := int64(0)
+	totalSkipped := int64(0)
 
 	for _, item := range runMetrics.ItemMetrics {
 		totalSuccess += item.SuccessCount
 		totalFailure += item.FailureCount
 		totalRequests += item.TotalRequests
+		totalSkipped += item.Skipped
 	}
 
 	duration := runMetrics.EndTime.Sub(runMetrics.StartTime)
-	throughput := float64(totalRequests) / duration.Seconds()
-
-	fmt.Println(strings.Repeat("=", 60))
-	fmt.Printf("%s\n", colorize(colorBold+colorCyan, "🎯 EXECUTION COMPLETE"))
-	fmt.Println(strings.Repeat("=", 60))
-	fmt.Printf("Collection:     %s\n", runMetrics.CollectionName)
-	fmt.Printf("Total Requests: %s\n", colorize(colorCyan, fmt.Sprintf("%d", totalRequests)))
-	fmt.Printf("Successful:     %s (%.1f%%)\n", colorize(colorGreen, fmt.Sprintf("%d", totalSuccess)), float64(totalSuccess)/float64(totalRequests)*100)
-	fmt.Printf("Failed:         %s (%.1f%%)\n", colorize(colorRed, fmt.Sprintf("%d", totalFailure)), float64(totalFailure)/float64(totalRequests)*100)
-	fmt.Printf("Duration:       %s\n", colorize(colorYellow, formatDuration(duration)))
-	fmt.Printf("Throughput:     %s req/s\n", colorize(colorYellow, fmt.Sprintf("%.2f", throughput)))
-	fmt.Println(strings.Repeat("=", 60))
+	throughput := 0.0
+	if duration > 0 {
+		throughput = float64(totalRequests) / duration.Seconds()
+	}
+
+	screen.Println(strings.Repeat("=", 60))
+	screen.Printf("%s\n", colorize(colorBold+colorCyan, "🎯 EXECUTION COMPLETE"))
+	screen.Println(strings.Repeat("=", 60))
+	screen.Printf("Collection:     %s\n", runMetrics.CollectionName)
+	screen.Printf("Total Requests: %s\n", colorize(colorCyan, fmt.Sprintf("%d", totalRequests)))
+	screen.Printf("Successful:     %s (%.1f%%)\n", colorize(colorGreen, fmt.Sprintf("%d", totalSuccess)), percentOf(totalSuccess, totalRequests))
+	screen.Printf("Failed:         %s (%.1f%%)\n", colorize(colorRed, fmt.Sprintf("%d", totalFailure)), percentOf(totalFailure, totalRequests))
+	if totalSkipped > 0 {
+		screen.Printf("Skipped:        %s (not sent, run aborted by a canary)\n", colorize(colorRed, fmt.Sprintf("%d", totalSkipped)))
+	}
+	if runMetrics.TokenFetches > 0 {
+		screen.Printf("Token Fetches:  %d (%s failed)\n", runMetrics.TokenFetches, colorize(colorRed, fmt.Sprintf("%d", runMetrics.TokenFetchFailures)))
+	}
+	screen.Printf("Duration:       %s\n", colorize(colorYellow, formatDuration(duration)))
+	screen.Printf("Throughput:     %s req/s\n", colorize(colorYellow, fmt.Sprintf("%.2f", throughput)))
+	screen.Println(strings.Repeat("=", 60))
 }
 
 // getRecordInfo creates a brief string representation of a CSV record for logging
@@ -1012,6 +1915,19 @@ func replaceTemplateVariables(template string, data map[string]string) string {
 
 // ReadCSV reads a CSV file and returns its contents as a slice of maps
 func ReadCSV(filepath string) ([]map[string]string, error) {
+	var rows []map[string]string
+	if _, err := streamCSV(filepath, func(row map[string]string) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// streamCSV passes the rows of a CSV file to fn one at a time, in file order,
+// and returns the headers. An error from fn stops the read and is returned.
+func streamCSV(filepath string, fn func(row map[string]string) error) ([]string, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("error opening file: %v", err)
@@ -1019,34 +1935,39 @@ func ReadCSV(filepath string) ([]map[string]string, error) {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error reading CSV: %v", err)
 	}
-
-	if len(records) == 0 {
-		return nil, fmt.Errorf("CSV file is empty")
-	}
-
-	headers := records[0]
 	if len(headers) == 0 {
 		return nil, fmt.Errorf("CSV file has no headers")
 	}
 
-	var rows []map[string]string
-	for i := 1; i < len(records); i++ {
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %v", err)
+		}
 		row := make(map[string]string)
 		for j, header := range headers {
-			if j < len(records[i]) {
-				row[header] = records[i][j]
+			if j < len(record) {
+				row[header] = record[j]
 			} else {
 				row[header] = ""
 			}
 		}
-		rows = append(rows, row)
+		if err := fn(row); err != nil {
+			return nil, err
+		}
 	}
 
-	return rows, nil
+	return headers, nil
 }
 
 // ReplaceJSONValues replaces values in a JSON string with values from CSV data
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunBatchMasksSecretsInURL checks that a {{secret:env:...}} reference
+// resolved into a request URL is sent but never printed or traced
+func TestRunBatchMasksSecretsInURL(t *testing.T) {
+	const secret = "s3cr3t-path-token"
+	t.Setenv("BACKFILL_TEST_PATH_TOKEN", secret)
+
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sent = append(sent, req.URL.Path)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	collection := filepath.Join(dir, "collection.json")
+	csvFile := filepath.Join(dir, "data.csv")
+	traceFile := filepath.Join(dir, "traces.json")
+	writeTestFile(t, collection, `{"info":{"name":"Secrets"},"item":[{"name":"Get","request":{"method":"GET",`+
+		`"url":{"raw":"`+server.URL+`/{{secret:env:BACKFILL_TEST_PATH_TOKEN}}/{{id}}"}}}]}`)
+	writeTestFile(t, csvFile, "id\n1\n")
+
+	var out bytes.Buffer
+	defer func(saved *console) { screen = saved }(screen)
+	screen = &console{w: &out}
+
+	RunBatch(RunConfig{
+		Collection:  collection,
+		CSV:         csvFile,
+		Threads:     1,
+		OutputDir:   dir,
+		MetricsFile: filepath.Join(dir, "metrics.json"),
+		TraceFile:   traceFile,
+	})
+
+	if len(sent) != 1 || sent[0] != "/"+secret+"/1" {
+		t.Fatalf("sent paths = %v, want [/%s/1]", sent, secret)
+	}
+	if !strings.Contains(out.String(), "Processing: Get") {
+		t.Fatalf("run output is missing the item:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), secret) {
+		t.Errorf("run output contains the secret:\n%s", out.String())
+	}
+	traces, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(traces), secret) {
+		t.Errorf("traces contain the secret:\n%s", traces)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+	"golang.org/x/term"
+)
+
+// VaultPassphraseEnv supplies the vault passphrase without a prompt (CI/CD)
+const VaultPassphraseEnv = "BACKFILL_VAULT_PASSPHRASE"
+
+// secretRefPattern matches {{secret:env:NAME}}, {{secret:file:/path}} and {{secret:vault:NAME}}
+var secretRefPattern = regexp.MustCompile(`\{\{\s*secret:([a-z]+):([^}]*?)\s*\}\}`)
+
+// DefaultVaultPath returns $BACKFILL_VAULT, or ~/.backfill-tool/vault.age
+func DefaultVaultPath() string {
+	if path := os.Getenv("BACKFILL_VAULT"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vault.age"
+	}
+	return filepath.Join(home, ".backfill-tool", "vault.age")
+}
+
+// secretResolver replaces secret references with their values. Each reference
+// is resolved once per run and the vault is decrypted at most once.
+type secretResolver struct {
+	vaultPath string
+	vault     map[string]string
+	resolved  map[string]string // Reference text -> value
+}
+
+// newSecretResolver creates a resolver reading vault references from vaultPath
+func newSecretResolver(vaultPath string) *secretResolver {
+	if vaultPath == "" {
+		vaultPath = DefaultVaultPath()
+	}
+	return &secretResolver{
+		vaultPath: vaultPath,
+		resolved:  make(map[string]string),
+	}
+}
+
+// ResolveString replaces every secret reference in text
+func (s *secretResolver) ResolveString(text string) (string, error) {
+	return s.replace(text, func(value string) string { return value })
+}
+
+// ResolveJSON replaces secret references inside the string literals of a JSON
+// document, escaping values so quotes or newlines cannot break the document
+func (s *secretResolver) ResolveJSON(data []byte) ([]byte, error) {
+	text, err := s.replace(string(data), func(value string) string {
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	})
+	return []byte(text), err
+}
+
+// References maps every resolved value back to its reference so output can
+// show the reference instead of the secret
+func (s *secretResolver) References() map[string]string {
+	refs := make(map[string]string, len(s.resolved))
+	for ref, value := range s.resolved {
+		refs[value] = ref
+	}
+	return refs
+}
+
+func (s *secretResolver) replace(text string, encode func(string) string) (string, error) {
+	var firstErr error
+	result := secretRefPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		value, err := s.resolve(ref)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ref
+		}
+		return encode(value)
+	})
+	return result, firstErr
+}
+
+func (s *secretResolver) resolve(ref string) (string, error) {
+	if value, ok := s.resolved[ref]; ok {
+		return value, nil
+	}
+
+	match := secretRefPattern.FindStringSubmatch(ref)
+	source, name := match[1], match[2]
+
+	var value string
+	switch source {
+	case "env":
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable %s is not set", ref, name)
+		}
+		value = v
+
+	case "file":
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %v", ref, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+
+	case "vault":
+		if s.vault == nil {
+			passphrase, err := ReadPassphrase("Vault passphrase: ", false)
+			if err != nil {
+				return "", err
+			}
+			if s.vault, err = LoadVault(s.vaultPath, passphrase); err != nil {
+				return "", err
+			}
+		}
+		v, ok := s.vault[name]
+		if !ok {
+			return "", fmt.Errorf("secret %s: %s is not in vault %s", ref, name, s.vaultPath)
+		}
+		value = v
+
+	default:
+		return "", fmt.Errorf("secret %s: unknown source %q (use env, file or vault)", ref, source)
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("secret %s is empty", ref)
+	}
+	s.resolved[ref] = value
+	return value, nil
+}
+
+// ReadPassphrase reads the vault passphrase from BACKFILL_VAULT_PASSPHRASE or
+// prompts on the terminal without echo, asking twice when confirm is set
+func ReadPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(VaultPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("vault passphrase required: set %s or run in a terminal", VaultPassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %v", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("error reading passphrase: %v", err)
+		}
+		if !bytes.Equal(passphrase, again) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
+// LoadVault decrypts a vault file. A missing file is an empty vault.
+func LoadVault(path, passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading vault: %v", err)
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting vault %s (wrong passphrase?): %v", path, err)
+	}
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting vault %s: %v", path, err)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("error parsing vault %s: %v", path, err)
+	}
+	return secrets, nil
+}
+
+// SaveVault encrypts secrets with the passphrase and replaces the vault file
+func SaveVault(path, passphrase string, secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	var encrypted bytes.Buffer
+	writer, err := age.Encrypt(&encrypted, recipient)
+	if err != nil {
+		return fmt.Errorf("error encrypting vault: %v", err)
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		return fmt.Errorf("error encrypting vault: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error encrypting vault: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating vault directory: %v", err)
+	}
+	// Write next to the vault and rename so a failed write never corrupts it
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encrypted.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing vault: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing vault: %v", err)
+	}
+	return nil
+}
+
+// SecretNames returns the sorted names stored in a vault
+func SecretNames(secrets map[string]string) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// itemSelector decides which requests of a collection run. Patterns are names,
+// slash separated paths from the collection root like "Users/Create", or globs
+// of either. Item patterns match requests, folder patterns select everything
+// inside a matching folder, and exclude patterns drop requests or folders.
+type itemSelector struct {
+	items   []string // With folders empty, every request is selected
+	folders []string
+	exclude []string
+}
+
+// SelectionReport records the item selection of a run in the metrics file
+type SelectionReport struct {
+	Items    []string `json:"items,omitempty"`
+	Folders  []string `json:"folders,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	Selected []string `json:"selected"` // Paths of the requests that ran
+	Skipped  []string `json:"skipped"`
+}
+
+// newItemSelector validates the item, folder and exclude patterns
+func newItemSelector(items, folders, exclude []string) (itemSelector, error) {
+	for _, patterns := range [][]string{items, folders, exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return itemSelector{}, fmt.Errorf("invalid item pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return itemSelector{items: items, folders: folders, exclude: exclude}, nil
+}
+
+// active reports whether any pattern was given
+func (s itemSelector) active() bool {
+	return len(s.items) > 0 || len(s.folders) > 0 || len(s.exclude) > 0
+}
+
+// selected reports whether the request at itemPath runs
+func (s itemSelector) selected(itemPath string) bool {
+	segments := strings.Split(itemPath, "/")
+	last := len(segments) - 1
+	if matchesSegments(s.exclude, segments, 0, last) {
+		return false
+	}
+	if len(s.items) == 0 && len(s.folders) == 0 {
+		return true
+	}
+	return matchesSegments(s.items, segments, last, last) || matchesSegments(s.folders, segments, 0, last-1)
+}
+
+// hasSelected reports whether a folder contains any request that runs
+func (s itemSelector) hasSelected(item PostmanItem, itemPath string) bool {
+	if len(item.Item) == 0 {
+		return s.selected(itemPath)
+	}
+	for _, child := range item.Item {
+		if s.hasSelected(child, joinItemPath(itemPath, child.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// report lists the selected and skipped requests of a collection
+func (s itemSelector) report(items []PostmanItem) *SelectionReport {
+	report := &SelectionReport{
+		Items:    s.items,
+		Folders:  s.folders,
+		Exclude:  s.exclude,
+		Selected: []string{},
+		Skipped:  []string{},
+	}
+	walkRequests(items, "", func(item PostmanItem, itemPath string) {
+		if s.selected(itemPath) {
+			report.Selected = append(report.Selected, itemPath)
+		} else {
+			report.Skipped = append(report.Skipped, itemPath)
+		}
+	})
+	return report
+}
+
+// matchesSegments reports whether a pattern matches one of the segments
+// first..last of a path, either by its path from the root or by name alone
+func matchesSegments(patterns, segments []string, first, last int) bool {
+	for _, pattern := range patterns {
+		for i := first; i <= last; i++ {
+			if globMatch(pattern, strings.Join(segments[:i+1], "/")) || globMatch(pattern, segments[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func globMatch(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// joinItemPath appends an item name to its parent folder path
+func joinItemPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+// walkRequests calls fn for every request below items in collection order
+func walkRequests(items []PostmanItem, parent string, fn func(item PostmanItem, itemPath string)) {
+	for _, item := range items {
+		itemPath := joinItemPath(parent, item.Name)
+		if len(item.Item) > 0 {
+			walkRequests(item.Item, itemPath, fn)
+			continue
+		}
+		fn(item, itemPath)
+	}
+}
+
+// ListCollection prints the item tree of a collection with indices and methods.
+// When selectors are given, requests they skip are shown dimmed.
+func ListCollection(collectionPath string, items, folders, exclude []string) error {
+	data, err := os.ReadFile(collectionPath)
+	if err != nil {
+		return fmt.Errorf("error opening collection file '%s': %v", collectionPath, err)
+	}
+	var collection PostmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return fmt.Errorf("error parsing collection JSON: %v", err)
+	}
+	selector, err := newItemSelector(items, folders, exclude)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 %s\n", collection.Info.Name)
+	var total, selected int
+	var print func(items []PostmanItem, parent, prefix string, depth int)
+	print = func(items []PostmanItem, parent, prefix string, depth int) {
+		for i, item := range items {
+			index := fmt.Sprintf("%s%d", prefix, i+1)
+			itemPath := joinItemPath(parent, item.Name)
+			indent := strings.Repeat("  ", depth)
+			if len(item.Item) > 0 {
+				fmt.Printf("%-8s %s%s\n", index, indent, colorize(colorCyan, "📁 "+item.Name))
+				print(item.Item, itemPath, index+".", depth+1)
+				continue
+			}
+
+			total++
+			line := fmt.Sprintf("%-8s %s%-7s %s", index, indent, strings.ToUpper(item.Request.Method), item.Name)
+			if selector.selected(itemPath) {
+				selected++
+				fmt.Println(line)
+			} else {
+				fmt.Println(colorize(colorGray, line+"  (skipped)"))
+			}
+		}
+	}
+	print(collection.Item, "", "", 0)
+
+	if selector.active() {
+		fmt.Printf("\n%d of %d requests selected\n", selected, total)
+	} else {
+		fmt.Printf("\n%d requests\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// rowSerializer gives every worker its own queue and sends all rows with the
+// same key column values to the same worker. A worker handles its rows one at
+// a time in CSV order (retries included), so updates to one entity cannot race
+// while different keys still run concurrently.
+type rowSerializer struct {
+	columns []string
+}
+
+// newRowSerializer returns nil without --serialize-by. columns are the CSV
+// headers the key columns must be among.
+func newRowSerializer(config RunConfig, columns map[string]bool) (*rowSerializer, error) {
+	if len(config.SerializeBy) == 0 {
+		return nil, nil
+	}
+	for _, column := range config.SerializeBy {
+		if !columns[column] {
+			return nil, fmt.Errorf("serialize column %q is not in the CSV file", column)
+		}
+	}
+	// Random canary rows run ahead of earlier rows of the same key
+	if config.CanaryRows > 0 && config.CanaryRandom {
+		return nil, fmt.Errorf("--serialize-by cannot be combined with --canary-random")
+	}
+	return &rowSerializer{columns: config.SerializeBy}, nil
+}
+
+// worker returns the index of the worker that sends a row
+func (s *rowSerializer) worker(record csvRecord, workers int) int {
+	h := fnv.New64a()
+	for _, column := range s.columns {
+		value := record.Data[column]
+		fmt.Fprintf(h, "%d:%s", len(value), value)
+	}
+	return int(h.Sum64() % uint64(workers))
+}
+
+// queues distributes records over one queue per worker, each sized for the
+// rows it receives, and closes them
+func (s *rowSerializer) queues(records []csvRecord, workers int) []chan csvRecord {
+	assigned := make([]int, len(records))
+	counts := make([]int, workers)
+	for i, record := range records {
+		assigned[i] = s.worker(record, workers)
+		counts[assigned[i]]++
+	}
+
+	queues := make([]chan csvRecord, workers)
+	for i := range queues {
+		queues[i] = make(chan csvRecord, counts[i])
+	}
+	for i, record := range records {
+		queues[assigned[i]] <- record
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	return queues
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// tlsVersions maps --tls-min-version values to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// hostClientCert is a client certificate used only for one host (or *.domain wildcard)
+type hostClientCert struct {
+	host        string
+	certificate tls.Certificate
+}
+
+// buildTLSConfig creates the client TLS configuration shared by all connections.
+// Per-host client certificates are returned separately because a tls.Config
+// cannot choose a certificate based on the server being dialed.
+func buildTLSConfig(config RunConfig) (*tls.Config, []hostClientCert, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.TLSMinVersion != "" {
+		version, ok := tlsVersions[config.TLSMinVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", config.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no PEM certificates found in CA bundle %s", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, nil, fmt.Errorf("both a client certificate and a client key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var hostCerts []hostClientCert
+	for _, spec := range config.HostClientCerts {
+		hostCert, err := parseHostClientCert(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		hostCerts = append(hostCerts, hostCert)
+	}
+
+	return tlsConfig, hostCerts, nil
+}
+
+// parseHostClientCert parses "host=cert.pem:key.pem" and loads the key pair
+func parseHostClientCert(spec string) (hostClientCert, error) {
+	host, files, ok := strings.Cut(spec, "=")
+	certFile, keyFile, ok2 := strings.Cut(files, ":")
+	if !ok || !ok2 || host == "" || certFile == "" || keyFile == "" {
+		return hostClientCert{}, fmt.Errorf("invalid host client certificate %q (expected host=cert.pem:key.pem)", spec)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return hostClientCert{}, fmt.Errorf("error loading client certificate for %s: %v", host, err)
+	}
+	return hostClientCert{host: strings.ToLower(host), certificate: cert}, nil
+}
+
+// hostRoutingTransport sends requests for hosts with their own client
+// certificate through a dedicated transport, and everything else through
+// the default one. Each transport keeps its own connection pool.
+type hostRoutingTransport struct {
+	defaultTransport *http.Transport
+	hosts            map[string]*http.Transport // exact host or "*.domain"
+}
+
+// newHostRoutingTransport clones base once per host certificate
+func newHostRoutingTransport(base *http.Transport, hostCerts []hostClientCert) *hostRoutingTransport {
+	rt := &hostRoutingTransport{
+		defaultTransport: base,
+		hosts:            make(map[string]*http.Transport),
+	}
+	for _, hc := range hostCerts {
+		transport := base.Clone()
+		transport.TLSClientConfig.Certificates = []tls.Certificate{hc.certificate}
+		rt.hosts[hc.host] = transport
+	}
+	return rt
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *hostRoutingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.transportFor(req.URL.Hostname()).RoundTrip(req)
+}
+
+// transportFor finds the transport for a host, trying wildcard parents after an exact match
+func (rt *hostRoutingTransport) transportFor(host string) *http.Transport {
+	host = strings.ToLower(host)
+	if transport, ok := rt.hosts[host]; ok {
+		return transport
+	}
+	for domain := host; strings.Contains(domain, "."); {
+		_, domain, _ = strings.Cut(domain, ".")
+		if transport, ok := rt.hosts["*."+domain]; ok {
+			return transport
+		}
+	}
+	return rt.defaultTransport
+}
+
+// printInsecureWarning makes it obvious that certificate verification is off
+func printInsecureWarning() {
+	line := strings.Repeat("!", 70)
+	fmt.Fprintln(os.Stderr, colorize(colorRed+colorBold, line))
+	fmt.Fprintln(os.Stderr, colorize(colorRed+colorBold, "WARNING: TLS certificate verification is DISABLED (--insecure-skip-verify)"))
+	fmt.Fprintln(os.Stderr, colorize(colorRed+colorBold, "Any server can impersonate your API and read tokens and data in transit."))
+	fmt.Fprintln(os.Stderr, colorize(colorRed+colorBold, line))
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLP span kind and status codes (see opentelemetry-proto trace.proto)
+const (
+	spanKindClient  = 3
+	spanStatusOK    = 1
+	spanStatusError = 2
+
+	// tracerBatchSize is the number of finished spans buffered before an export
+	tracerBatchSize = 256
+	// tracerQueueSize is the number of batches waiting for the exporter; spans
+	// are dropped beyond it so a slow collector never holds up the workers
+	tracerQueueSize = 16
+)
+
+// Span is a single traced request. Every request gets its own trace so the
+// trace ID identifies exactly one row on both sides of the wire.
+type Span struct {
+	TraceID       [16]byte
+	SpanID        [8]byte
+	Name          string
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	StatusError   bool
+	StatusMessage string
+}
+
+// TraceIDString returns the hex encoded trace ID
+func (s *Span) TraceIDString() string {
+	return hex.EncodeToString(s.TraceID[:])
+}
+
+// SpanIDString returns the hex encoded span ID
+func (s *Span) SpanIDString() string {
+	return hex.EncodeToString(s.SpanID[:])
+}
+
+// Traceparent returns the W3C trace context header value for this span
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceIDString(), s.SpanIDString())
+}
+
+// SetAttribute records a string, int or bool attribute on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+// Tracer creates request spans and exports them in OTLP/JSON format,
+// either appended to a file or posted to an OTLP/HTTP collector.
+type Tracer struct {
+	serviceName string
+	endpoint    string
+	file        *os.File
+	client      *http.Client
+
+	batches chan []*Span  // Batches for the exporter goroutine
+	done    chan struct{} // Closed when the exporter has finished
+
+	mu      sync.Mutex
+	pending []*Span
+	errors  int
+	dropped int // Spans dropped because the export queue was full
+}
+
+// NewTracer creates a tracer exporting to an OTLP/HTTP endpoint, a file, or both.
+// It returns nil when neither destination is configured, which disables tracing.
+func NewTracer(serviceName, endpoint, filePath string) (*Tracer, error) {
+	if endpoint == "" && filePath == "" {
+		return nil, nil
+	}
+
+	t := &Tracer{
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		batches:     make(chan []*Span, tracerQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	if endpoint != "" {
+		endpoint = strings.TrimRight(endpoint, "/")
+		if !strings.HasSuffix(endpoint, "/v1/traces") {
+			endpoint += "/v1/traces"
+		}
+		t.endpoint = endpoint
+	}
+
+	if filePath != "" {
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %v", err)
+		}
+		t.file = file
+	}
+
+	go t.exportLoop()
+	return t, nil
+}
+
+// exportLoop exports queued batches until Shutdown closes the queue
+func (t *Tracer) exportLoop() {
+	defer close(t.done)
+	for batch := range t.batches {
+		t.export(batch)
+	}
+}
+
+// StartSpan begins a new root client span
+func (t *Tracer) StartSpan(name string) *Span {
+	span := &Span{
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+	rand.Read(span.TraceID[:])
+	rand.Read(span.SpanID[:])
+	return span
+}
+
+// EndSpan finishes a span and queues it for export
+func (t *Tracer) EndSpan(span *Span) {
+	span.EndTime = time.Now()
+
+	t.mu.Lock()
+	t.pending = append(t.pending, span)
+	var batch []*Span
+	if len(t.pending) >= tracerBatchSize {
+		batch = t.pending
+		t.pending = nil
+	}
+	t.mu.Unlock()
+
+	if batch == nil {
+		return
+	}
+	select {
+	case t.batches <- batch:
+	default:
+		t.mu.Lock()
+		t.dropped += len(batch)
+		t.mu.Unlock()
+	}
+}
+
+// Shutdown exports any buffered spans, waits for the exporter and closes the
+// trace file. It returns an error if any export failed or spans were dropped.
+func (t *Tracer) Shutdown() error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		t.batches <- batch
+	}
+	close(t.batches)
+	<-t.done
+	if t.file != nil {
+		t.file.Close()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var problems []string
+	if t.errors > 0 {
+		problems = append(problems, fmt.Sprintf("%d trace export(s) failed", t.errors))
+	}
+	if t.dropped > 0 {
+		problems = append(problems, fmt.Sprintf("%d span(s) dropped because the exporter fell behind", t.dropped))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// export writes a batch of spans to the configured destinations
+func (t *Tracer) export(spans []*Span) {
+	payload, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		t.recordError()
+		return
+	}
+
+	if t.file != nil {
+		t.mu.Lock()
+		_, err := t.file.Write(append(payload, '\n'))
+		t.mu.Unlock()
+		if err != nil {
+			t.recordError()
+		}
+	}
+
+	if t.endpoint != "" {
+		resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			t.recordError()
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			t.recordError()
+		}
+	}
+}
+
+func (t *Tracer) recordError() {
+	t.mu.Lock()
+	t.errors++
+	t.mu.Unlock()
+}
+
+// encode converts spans into an OTLP ExportTraceServiceRequest in JSON form
+func (t *Tracer) encode(spans []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		status := map[string]interface{}{"code": spanStatusOK}
+		if span.StatusError {
+			status = map[string]interface{}{"code": spanStatusError, "message": span.StatusMessage}
+		}
+		encoded = append(encoded, map[string]interface{}{
+			"traceId":           span.TraceIDString(),
+			"spanId":            span.SpanIDString(),
+			"name":              span.Name,
+			"kind":              spanKindClient,
+			"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+			"status":            status,
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{
+			{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": t.serviceName}),
+				},
+				"scopeSpans": []map[string]interface{}{
+					{
+						"scope": map[string]interface{}{"name": "backfill-tool"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+// otlpAttributes converts a map into the OTLP KeyValue list encoding
+func otlpAttributes(attrs map[string]interface{}) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		var encoded map[string]interface{}
+		switch v := value.(type) {
+		case int:
+			encoded = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			encoded = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			encoded = map[string]interface{}{"boolValue": v}
+		default:
+			encoded = map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+		}
+		list = append(list, map[string]interface{}{"key": key, "value": encoded})
+	}
+	return list
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadVariables reads variable files in order, later files overriding earlier
+// ones, then applies inline variables on top
+func loadVariables(files []string, inline map[string]string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, file := range files {
+		values, err := readVariableFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading variable file %s: %v", file, err)
+		}
+		for key, value := range values {
+			variables[key] = value
+		}
+	}
+	for key, value := range inline {
+		variables[key] = value
+	}
+	return variables, nil
+}
+
+// readVariableFile reads a Postman environment export, a flat JSON or YAML
+// object, or KEY=VALUE lines (.env), chosen by file extension
+func readVariableFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var environment struct {
+			Values []struct {
+				Key     string      `json:"key"`
+				Value   interface{} `json:"value"`
+				Enabled *bool       `json:"enabled"`
+			} `json:"values"`
+		}
+		if err := json.Unmarshal(data, &environment); err == nil && environment.Values != nil {
+			values := make(map[string]string)
+			for _, v := range environment.Values {
+				if v.Enabled == nil || *v.Enabled {
+					values[v.Key] = scalarString(v.Value)
+				}
+			}
+			return values, nil
+		}
+		var flat map[string]interface{}
+		if err := json.Unmarshal(data, &flat); err != nil {
+			return nil, err
+		}
+		return stringValues(flat), nil
+
+	case ".yaml", ".yml":
+		var flat map[string]interface{}
+		if err := yaml.Unmarshal(data, &flat); err != nil {
+			return nil, err
+		}
+		return stringValues(flat), nil
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, nil
+}
+
+func stringValues(flat map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(flat))
+	for key, value := range flat {
+		values[key] = scalarString(value)
+	}
+	return values
+}
+
+func scalarString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// withVariables returns a row with static variables added; row values win
+func withVariables(row, variables map[string]string) map[string]string {
+	if len(variables) == 0 {
+		return row
+	}
+	merged := make(map[string]string, len(row)+len(variables))
+	for key, value := range variables {
+		merged[key] = value
+	}
+	for key, value := range row {
+		merged[key] = value
+	}
+	return merged
+}
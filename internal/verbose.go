@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Verbose levels (-v, -vv, -vvv); each level adds to the one before
+const (
+	VerboseRequests = 1 // One line per request with status and latency
+	VerboseHeaders  = 2 // Rendered request headers and body, response headers
+	VerboseBodies   = 3 // Response body and connection details
+)
+
+// requestDetail is what a request sent and received, kept for --verbose from
+// VerboseHeaders up and for --record-requests
+type requestDetail struct {
+	requestURL     string // Unmasked
+	requestHeader  http.Header
+	requestBody    string
+	responseStatus string // e.g. "HTTP/1.1 200 OK"; empty without a response
+	responseHeader http.Header
+	responseBody   string // VerboseBodies only
+
+	// Connection details (VerboseBodies only)
+	remoteAddr string
+	idleTime   time.Duration // How long a reused connection was idle
+	dnsTime    time.Duration
+	connTime   time.Duration
+	tlsState   *tls.ConnectionState
+}
+
+// newRequestDetail returns nil when neither the verbose level nor recording needs request details
+func newRequestDetail(config RunConfig) *requestDetail {
+	if config.Verbose < VerboseHeaders && !config.RecordRequests {
+		return nil
+	}
+	return &requestDetail{}
+}
+
+// printVerbose prints an attempt of a request at the configured verbose level,
+// with secrets masked. With --verbose-failures only failed attempts are shown.
+func (r *batchRunner) printVerbose(item PostmanItem, record csvRecord, result RequestResult) {
+	level := r.config.Verbose
+	if level < VerboseRequests || r.config.DryRun || (r.config.VerboseFailures && result.Success) {
+		return
+	}
+	secrets := r.rowSecrets(item, record)
+	result = r.redactor.Result(result, secrets)
+
+	status := "no response"
+	if result.StatusCode != 0 {
+		status = fmt.Sprintf("%d", result.StatusCode)
+	}
+	if result.Success {
+		status = colorize(colorGreen, status)
+	} else {
+		status = colorize(colorRed, status)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %s %s", colorize(colorGray, fmt.Sprintf("[%s] row %d", item.Name, record.Index)),
+		colorize(colorPurple, result.Method), result.URL, status, colorize(colorGray, fmt.Sprintf("%dms", result.ResponseTime.Milliseconds())))
+	if result.Retries > 0 {
+		fmt.Fprintf(&b, " %s", colorize(colorGray, fmt.Sprintf("(attempt %d)", result.Retries+1)))
+	}
+	b.WriteString("\n")
+	if !result.Success && result.Error != "" {
+		fmt.Fprintf(&b, "    %s\n", colorize(colorRed, result.Error))
+	}
+
+	if detail := result.detail; detail != nil && level >= VerboseHeaders {
+		if level >= VerboseBodies && detail.remoteAddr != "" {
+			fmt.Fprintf(&b, "    %s\n", colorize(colorGray, "* "+describeConnection(result, detail)))
+		}
+		writeHeaders(&b, "> ", detail.requestHeader, r.redactor, secrets)
+		writeBody(&b, "> ", r.redactor.String(detail.requestBody, secrets))
+		if detail.responseStatus != "" {
+			fmt.Fprintf(&b, "    < %s\n", detail.responseStatus)
+			writeHeaders(&b, "< ", detail.responseHeader, r.redactor, secrets)
+		}
+		if level >= VerboseBodies {
+			writeBody(&b, "< ", r.redactor.String(detail.responseBody, secrets))
+		}
+	}
+
+	screen.Printf("%s", b.String())
+}
+
+// describeConnection summarizes how a request reached the server
+func describeConnection(result RequestResult, detail *requestDetail) string {
+	parts := []string{"Connected to " + detail.remoteAddr}
+	if result.ConnReused {
+		parts = append(parts, fmt.Sprintf("reused connection (idle %dms)", detail.idleTime.Milliseconds()))
+	} else if detail.dnsTime > 0 {
+		parts = append(parts, fmt.Sprintf("new connection (DNS %dms, connect %dms)", detail.dnsTime.Milliseconds(), detail.connTime.Milliseconds()))
+	} else {
+		parts = append(parts, fmt.Sprintf("new connection (connect %dms)", detail.connTime.Milliseconds()))
+	}
+	if result.Protocol != "" {
+		parts = append(parts, result.Protocol)
+	}
+	if state := detail.tlsState; state != nil {
+		tlsInfo := tls.VersionName(state.Version) + " " + tls.CipherSuiteName(state.CipherSuite)
+		if result.TLSHandshake > 0 {
+			tlsInfo += fmt.Sprintf(", handshake %dms", result.TLSHandshake.Milliseconds())
+		}
+		parts = append(parts, tlsInfo)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeHeaders writes headers in name order, one line per value, masked like
+// the headers of a dry run
+func writeHeaders(b *strings.Builder, prefix string, header http.Header, redactor *redactor, secrets []string) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(b, "    %s%s: %s\n", prefix, name, redactor.Header(name, value, secrets))
+		}
+	}
+}
+
+// writeBody writes a body after a blank marker line, prefixing each of its lines
+func writeBody(b *strings.Builder, prefix, body string) {
+	if body == "" {
+		return
+	}
+	fmt.Fprintf(b, "    %s\n", strings.TrimSpace(prefix))
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		fmt.Fprintf(b, "    %s%s\n", prefix, line)
+	}
+}
@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// whereNode is a parsed --where expression evaluated against one CSV row.
+//
+// Grammar:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = operand [ op operand ]     (a lone column is true when not empty)
+//	op         = "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~"
+//	operand    = column | `quoted column` | "string" | 'string' | number
+//
+// Values that both parse as numbers compare numerically, everything else as strings.
+type whereNode interface {
+	eval(row map[string]string) bool
+}
+
+type whereOperand struct {
+	column  string
+	literal string
+}
+
+func (o whereOperand) value(row map[string]string) string {
+	if o.column != "" {
+		return row[o.column]
+	}
+	return o.literal
+}
+
+type whereCompare struct {
+	left, right whereOperand
+	op          string
+	re          *regexp.Regexp // For =~ and !~
+}
+
+func (c whereCompare) eval(row map[string]string) bool {
+	left := c.left.value(row)
+	if c.op == "" {
+		return left != ""
+	}
+	right := c.right.value(row)
+
+	switch c.op {
+	case "=~":
+		return c.re.MatchString(left)
+	case "!~":
+		return !c.re.MatchString(left)
+	}
+
+	cmp := strings.Compare(left, right)
+	if l, err := strconv.ParseFloat(strings.TrimSpace(left), 64); err == nil {
+		if r, err := strconv.ParseFloat(strings.TrimSpace(right), 64); err == nil {
+			cmp = 0
+			if l < r {
+				cmp = -1
+			} else if l > r {
+				cmp = 1
+			}
+		}
+	}
+
+	switch c.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+type whereNot struct{ node whereNode }
+
+func (n whereNot) eval(row map[string]string) bool { return !n.node.eval(row) }
+
+type whereAnd []whereNode
+
+func (n whereAnd) eval(row map[string]string) bool {
+	for _, node := range n {
+		if !node.eval(row) {
+			return false
+		}
+	}
+	return true
+}
+
+type whereOr []whereNode
+
+func (n whereOr) eval(row map[string]string) bool {
+	for _, node := range n {
+		if node.eval(row) {
+			return true
+		}
+	}
+	return false
+}
+
+// whereToken is a lexical token of a --where expression
+type whereToken struct {
+	kind  string // "column", "string", "number", "op" or "end"
+	value string
+	pos   int // 1-based character position, for error messages
+}
+
+// whereParser is a recursive descent parser for --where expressions
+type whereParser struct {
+	tokens  []whereToken
+	next    int
+	columns map[string]bool
+}
+
+// parseWhere parses an expression, checking that every column it names exists
+func parseWhere(expr string, columns map[string]bool) (whereNode, error) {
+	tokens, err := tokenizeWhere(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where %q: %v", expr, err)
+	}
+	p := &whereParser{tokens: tokens, columns: columns}
+	node, err := p.parseOr()
+	if err == nil && p.peek().kind != "end" {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek().value, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where %q: %v", expr, err)
+	}
+	return node, nil
+}
+
+func (p *whereParser) peek() whereToken {
+	return p.tokens[p.next]
+}
+
+func (p *whereParser) take() whereToken {
+	token := p.tokens[p.next]
+	if token.kind != "end" {
+		p.next++
+	}
+	return token
+}
+
+func (p *whereParser) parseOr() (whereNode, error) {
+	var nodes whereOr
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if p.peek().value != "||" {
+			break
+		}
+		p.take()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *whereParser) parseAnd() (whereNode, error) {
+	var nodes whereAnd
+	for {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if p.peek().value != "&&" {
+			break
+		}
+		p.take()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *whereParser) parseUnary() (whereNode, error) {
+	switch token := p.peek(); {
+	case token.kind == "op" && token.value == "!":
+		p.take()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{node}, nil
+	case token.kind == "op" && token.value == "(":
+		p.take()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.value != ")" {
+			return nil, fmt.Errorf("expected ) at position %d", closing.pos)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whereParser) parseComparison() (whereNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	compare := whereCompare{left: left}
+
+	token := p.peek()
+	switch token.value {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		if left.column == "" {
+			return nil, fmt.Errorf("expected a comparison operator at position %d", token.pos)
+		}
+		return compare, nil
+	}
+	p.take()
+	compare.op = token.value
+
+	if compare.right, err = p.parseOperand(); err != nil {
+		return nil, err
+	}
+	if compare.op == "=~" || compare.op == "!~" {
+		if compare.right.column != "" {
+			return nil, fmt.Errorf("the pattern after %s must be a quoted string", compare.op)
+		}
+		if compare.re, err = regexp.Compile(compare.right.literal); err != nil {
+			return nil, err
+		}
+	}
+	return compare, nil
+}
+
+func (p *whereParser) parseOperand() (whereOperand, error) {
+	token := p.take()
+	switch token.kind {
+	case "column":
+		if !p.columns[token.value] {
+			return whereOperand{}, fmt.Errorf("unknown column %q at position %d (quote string values)", token.value, token.pos)
+		}
+		return whereOperand{column: token.value}, nil
+	case "string", "number":
+		return whereOperand{literal: token.value}, nil
+	case "end":
+		return whereOperand{}, fmt.Errorf("unexpected end of expression")
+	}
+	return whereOperand{}, fmt.Errorf("expected a column or value at position %d, got %q", token.pos, token.value)
+}
+
+var whereNumber = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?`)
+
+// tokenizeWhere splits an expression into tokens, ending with an "end" token
+func tokenizeWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '"' || c == '\'' || c == '`':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				value.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+			kind := "string"
+			if c == '`' {
+				kind = "column"
+			}
+			tokens = append(tokens, whereToken{kind: kind, value: value.String(), pos: i + 1})
+			i = j + 1
+
+		case whereNumber.MatchString(expr[i:]):
+			number := whereNumber.FindString(expr[i:])
+			tokens = append(tokens, whereToken{kind: "number", value: number, pos: i + 1})
+			i += len(number)
+
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' || expr[j] == '-' ||
+				expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, whereToken{kind: "column", value: expr[i:j], pos: i + 1})
+			i = j
+
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, whereToken{kind: "op", value: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	return append(tokens, whereToken{kind: "end", value: "end of expression", pos: len(expr) + 1}), nil
+}